@@ -0,0 +1,116 @@
+package geoindex
+
+import (
+	"math"
+	"runtime"
+	"sort"
+	"sync"
+)
+
+// KNearestBatch returns the k nearest points within maxDistance that match the accept criteria for each of queries,
+// like KNearest, in the order of queries. The queries in the same cell share the search of the points around the
+// cell and the cells are searched in parallel, so accept must be safe to call concurrently. The index must not be
+// modified until KNearestBatch returns.
+func (points *PointsIndex) KNearestBatch(queries []Point, k int, maxDistance Meters, accept func(p Point) bool) [][]Point {
+	results := make([][]Point, len(queries))
+
+	groups := make(map[cell][]int)
+	for i, query := range queries {
+		c := points.index.scheme.cellOf(query)
+		groups[c] = append(groups[c], i)
+	}
+
+	workers := runtime.GOMAXPROCS(0)
+	if _, expiring := points.index.newEntry().(*expiringSet); expiring {
+		// reading an expiring set removes the expired points from it
+		workers = 1
+	}
+	workers = min(workers, len(groups))
+
+	work := make(chan []int)
+	var wg sync.WaitGroup
+
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for group := range work {
+				points.kNearestGroup(queries, group, k, maxDistance, accept, results)
+			}
+		}()
+	}
+
+	for _, group := range groups {
+		work <- group
+	}
+	close(work)
+	wg.Wait()
+
+	return results
+}
+
+// kNearestGroup sets the results of the queries of group. It searches the points around all the queries in growing
+// rectangles, like nearest searches growing circles, until the kth nearest point of each query is within the
+// radius searched around it.
+func (points *PointsIndex) kNearestGroup(queries []Point, group []int, k int, maxDistance Meters, accept func(p Point) bool, results [][]Point) {
+	if len(group) == 1 || k <= 0 {
+		for _, i := range group {
+			results[i] = points.KNearest(queries[i], k, maxDistance, accept)
+		}
+		return
+	}
+
+	// the rectangle containing the queries of the group
+	minLat, maxLat := math.Inf(1), math.Inf(-1)
+	minLon, maxLon := math.Inf(1), math.Inf(-1)
+	for _, i := range group {
+		minLat, maxLat = math.Min(minLat, queries[i].Lat()), math.Max(maxLat, queries[i].Lat())
+		minLon, maxLon = math.Min(minLon, queries[i].Lon()), math.Max(maxLon, queries[i].Lon())
+	}
+	topLeft, bottomRight := &GeoPoint{"", maxLat, minLon}, &GeoPoint{"", minLat, maxLon}
+
+	pending := append(make([]int, 0, len(group)), group...)
+	candidates := make([]Point, 0)
+	nearest := make(nearestHeap, 0, k)
+
+	appendCandidate := func(point Point) bool {
+		if accept(point) {
+			candidates = append(candidates, point)
+		}
+		return true
+	}
+
+	searched := Meters(0)
+	for len(pending) > 0 {
+		searched = Meters(math.Min(float64(points.index.growRadius(searched)), float64(maxDistance)))
+		final := searched >= maxDistance || searched >= math.Pi*earthRadius
+
+		candidates = candidates[:0]
+		minLat, maxLat, minLon, maxLon := aroundRectBox(topLeft, bottomRight, searched)
+		points.RangeFunc(&GeoPoint{"", maxLat, minLon}, &GeoPoint{"", minLat, maxLon}, appendCandidate)
+
+		stillPending := pending[:0]
+		for _, i := range pending {
+			nearest = nearest[:0]
+			for _, candidate := range candidates {
+				if d := Distance(queries[i], candidate); d <= maxDistance {
+					nearest.offer(PointDistance{Point: candidate, Distance: d}, k)
+				}
+			}
+
+			// the points that are not candidates are farther than searched
+			if !final && (len(nearest) < k || nearest[0].Distance > searched) {
+				stillPending = append(stillPending, i)
+				continue
+			}
+
+			sort.Sort(byDistance(nearest))
+			results[i] = make([]Point, len(nearest))
+			for j, nearbyPoint := range nearest {
+				results[i][j] = nearbyPoint.Point
+			}
+		}
+
+		pending = stillPending
+	}
+}
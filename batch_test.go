@@ -0,0 +1,97 @@
+package geoindex
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func nearestDistances(point Point, nearest []Point) []Meters {
+	distances := make([]Meters, len(nearest))
+	for i, p := range nearest {
+		distances[i] = Distance(point, p)
+	}
+	return distances
+}
+
+func TestKNearestBatch(t *testing.T) {
+	rand.Seed(17)
+
+	for _, generatePoint := range []func() Point{randomPoint, randomPointWorldWide} {
+		points := randomPoints(2000, generatePoint)
+		// many queries share a cell
+		queries := append(randomPoints(200, generatePoint), pointsAround(charring, 100, 0.001)...)
+
+		for _, index := range []*PointsIndex{
+			NewPointsIndex(Km(0.5)),
+			NewPointsIndex(Km(5)),
+			NewHexPointsIndex(Km(1), 51.5),
+			NewQuadtreePointsIndex(16),
+		} {
+			for _, p := range points {
+				index.Add(p)
+			}
+
+			for _, k := range []int{0, 1, 10} {
+				for _, maxDistance := range []Meters{Km(1), Km(50)} {
+					results := index.KNearestBatch(queries, k, maxDistance, all)
+					assert.Equal(t, len(queries), len(results))
+
+					for i, query := range queries {
+						expected := index.KNearest(query, k, maxDistance, all)
+						assert.Equal(t, nearestDistances(query, expected), nearestDistances(query, results[i]))
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestKNearestBatchAccept(t *testing.T) {
+	index := NewPointsIndex(Km(0.5))
+	for _, station := range tubeStations() {
+		index.Add(station)
+	}
+
+	noEmbankment := func(p Point) bool {
+		return p.Id() != embankment.Id()
+	}
+
+	results := index.KNearestBatch([]Point{charring, oxford, charring}, 3, Km(5), noEmbankment)
+	assert.Equal(t, index.KNearest(charring, 3, Km(5), noEmbankment), results[0])
+	assert.Equal(t, index.KNearest(oxford, 3, Km(5), noEmbankment), results[1])
+	assert.Equal(t, results[0], results[2])
+
+	assert.Empty(t, index.KNearestBatch(nil, 3, Km(5), all))
+}
+
+func TestKNearestBatchExpiring(t *testing.T) {
+	index := NewExpiringPointsIndex(Km(0.5), Minutes(5))
+
+	currentTime := time.Now()
+	now = currentTime
+
+	index.Add(charring)
+	index.Add(embankment)
+
+	now = currentTime.Add(3 * time.Minute)
+	index.Add(leicester)
+
+	now = currentTime.Add(6 * time.Minute)
+	results := index.KNearestBatch([]Point{charring, embankment}, 3, Km(5), all)
+	assert.Equal(t, [][]Point{{leicester}, {leicester}}, results)
+
+	now = time.Time{}
+}
+
+func BenchmarkPointIndexKNearestBatch(b *testing.B) {
+	index := NewPointsIndex(Km(0.5))
+	addStopTimer(index, 10000, randomPoint, bench(b))
+	queries := pointsAround(charring, 1000, 0.01)
+
+	for i := 0; i < b.N; i++ {
+		index.KNearestBatch(queries, 5, Km(5), all)
+	}
+}
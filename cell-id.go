@@ -0,0 +1,200 @@
+package geoindex
+
+import (
+	"fmt"
+	"math"
+)
+
+// MaxCellLevel is the level of the smallest cells, about 2 by 2 cm at the equator.
+const MaxCellLevel = 30
+
+// CellID identifies a cell of a hierarchy that splits the lat/lon plane in 4 cells at each level. At level 0 a
+// single cell covers the whole earth, at level n there are 2^n rows of latitude and 2^n columns of longitude.
+// The cells of each level are numbered along a Hilbert curve, so the ids are sortable and close cells have close
+// ids. The descendants of a cell have ids in the contiguous range from RangeMin to RangeMax.
+//
+// The id stores the position of the cell on the curve followed by a single 1 bit, the position of this bit
+// determines the level, like S2 cell ids.
+type CellID uint64
+
+// CellIDOf returns the id of the cell at level that contains point.
+func CellIDOf(point Point, level int) CellID {
+	cells := float64(uint64(1) << MaxCellLevel)
+	x := uint64(math.Min(cells-1, (normalizeLon(point.Lon())-minLon)/360*cells))
+	y := uint64(math.Min(cells-1, (normalizeLat(point.Lat())-minLat)/180*cells))
+
+	return cellIDFromPosition(hilbertPosition(x, y, MaxCellLevel), MaxCellLevel).Parent(level)
+}
+
+// cellIDFromPosition returns the id of the cell at position on the Hilbert curve of level.
+func cellIDFromPosition(position uint64, level int) CellID {
+	shift := uint(2*(MaxCellLevel-level) + 1)
+	return CellID(position<<shift | 1<<(shift-1))
+}
+
+// cellIDFromCoords returns the id of the cell at level with column x and row y.
+func cellIDFromCoords(x, y uint64, level int) CellID {
+	return cellIDFromPosition(hilbertPosition(x, y, uint(level)), level)
+}
+
+func (id CellID) lsb() uint64 {
+	return uint64(id) & -uint64(id)
+}
+
+// IsValid returns true if id is the id of a cell.
+func (id CellID) IsValid() bool {
+	// the level bit must be at an even position
+	return id != 0 && id.lsb()&0x5555555555555555 != 0 && uint64(id) < 1<<(2*MaxCellLevel+1)
+}
+
+// Level returns the level of the cell, 0 for the whole earth up to MaxCellLevel.
+func (id CellID) Level() int {
+	level := MaxCellLevel
+	for lsb := id.lsb(); lsb > 1; lsb >>= 2 {
+		level--
+	}
+	return level
+}
+
+// Parent returns the cell at level that contains id. Level must not be greater than the level of id.
+func (id CellID) Parent(level int) CellID {
+	if level < 0 || level > id.Level() {
+		panic(fmt.Sprintf("Invalid parent level %d for cell at level %d", level, id.Level()))
+	}
+
+	lsb := uint64(1) << uint(2*(MaxCellLevel-level))
+	return CellID(uint64(id)&-lsb | lsb)
+}
+
+// Children returns the 4 cells of the next level that are within id, in Hilbert curve order.
+func (id CellID) Children() [4]CellID {
+	if id.Level() == MaxCellLevel {
+		panic("Cells at MaxCellLevel do not have children")
+	}
+
+	lsb := id.lsb()
+	childLsb := lsb >> 2
+	first := uint64(id) - lsb + childLsb
+
+	var children [4]CellID
+	for i := range children {
+		children[i] = CellID(first + uint64(i)*2*childLsb)
+	}
+
+	return children
+}
+
+// RangeMin returns the smallest id of the cells within id, at any level.
+func (id CellID) RangeMin() CellID {
+	return CellID(uint64(id) - (id.lsb() - 1))
+}
+
+// RangeMax returns the greatest id of the cells within id, at any level.
+func (id CellID) RangeMax() CellID {
+	return CellID(uint64(id) + (id.lsb() - 1))
+}
+
+// Contains returns true if other is id or a descendant of id.
+func (id CellID) Contains(other CellID) bool {
+	return other >= id.RangeMin() && other <= id.RangeMax()
+}
+
+// coords returns the column and row of the cell in its level.
+func (id CellID) coords() (x, y uint64) {
+	level := id.Level()
+	position := uint64(id) >> uint(2*(MaxCellLevel-level)+1)
+
+	return hilbertCoords(position, uint(level))
+}
+
+// Bounds returns the top left and bottom right corners of the cell.
+func (id CellID) Bounds() (topLeft Point, bottomRight Point) {
+	x, y := id.coords()
+	cells := float64(uint64(1) << uint(id.Level()))
+	width := 360 / cells
+	height := 180 / cells
+
+	left := minLon + float64(x)*width
+	bottom := minLat + float64(y)*height
+
+	return &GeoPoint{"", bottom + height, left}, &GeoPoint{"", bottom, left + width}
+}
+
+// Center returns the center of the cell.
+func (id CellID) Center() Point {
+	topLeft, bottomRight := id.Bounds()
+	return &GeoPoint{"", (topLeft.Lat() + bottomRight.Lat()) / 2, (topLeft.Lon() + bottomRight.Lon()) / 2}
+}
+
+func (id CellID) String() string {
+	return fmt.Sprintf("%d/%016x", id.Level(), uint64(id))
+}
+
+// hilbertPosition returns the position of column x and row y on the Hilbert curve filling a 2^order by 2^order
+// square.
+func hilbertPosition(x, y uint64, order uint) uint64 {
+	n := uint64(1) << order
+	position := uint64(0)
+
+	for s := n / 2; s > 0; s /= 2 {
+		rx := uint64(0)
+		if x&s > 0 {
+			rx = 1
+		}
+
+		ry := uint64(0)
+		if y&s > 0 {
+			ry = 1
+		}
+
+		position += s * s * ((3 * rx) ^ ry)
+		x, y = hilbertRotate(n, x, y, rx, ry)
+	}
+
+	return position
+}
+
+// hilbertCoords returns the column and the row at position on the Hilbert curve filling a 2^order by 2^order
+// square.
+func hilbertCoords(position uint64, order uint) (x, y uint64) {
+	n := uint64(1) << order
+
+	for s := uint64(1); s < n; s *= 2 {
+		rx := 1 & (position / 2)
+		ry := 1 & (position ^ rx)
+
+		x, y = hilbertRotate(s, x, y, rx, ry)
+		x += s * rx
+		y += s * ry
+		position /= 4
+	}
+
+	return x, y
+}
+
+func hilbertRotate(n, x, y, rx, ry uint64) (uint64, uint64) {
+	if ry == 0 {
+		if rx == 1 {
+			x = n - 1 - x
+			y = n - 1 - y
+		}
+
+		return y, x
+	}
+
+	return x, y
+}
+
+type cellIDs []CellID
+
+func (ids cellIDs) Len() int {
+	return len(ids)
+}
+
+func (ids cellIDs) Swap(i, j int) {
+	ids[i], ids[j] = ids[j], ids[i]
+}
+
+func (ids cellIDs) Less(i, j int) bool {
+	return ids[i] < ids[j]
+}
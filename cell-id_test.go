@@ -0,0 +1,68 @@
+package geoindex
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCellIDHierarchy(t *testing.T) {
+	leaf := CellIDOf(waterloo, MaxCellLevel)
+	assert.True(t, leaf.IsValid())
+	assert.Equal(t, MaxCellLevel, leaf.Level())
+
+	for level := 0; level <= MaxCellLevel; level++ {
+		id := CellIDOf(waterloo, level)
+
+		assert.True(t, id.IsValid())
+		assert.Equal(t, level, id.Level())
+		assert.Equal(t, id, leaf.Parent(level))
+		assert.True(t, id.Contains(leaf))
+		assert.True(t, id.RangeMin() <= leaf && leaf <= id.RangeMax())
+
+		topLeft, bottomRight := id.Bounds()
+		assert.True(t, between(waterloo.Lat(), bottomRight.Lat(), topLeft.Lat()))
+		assert.True(t, between(waterloo.Lon(), topLeft.Lon(), bottomRight.Lon()))
+		assert.Equal(t, id, CellIDOf(id.Center(), level))
+
+		if level < MaxCellLevel {
+			children := id.Children()
+			assert.Contains(t, children, CellIDOf(waterloo, level+1))
+
+			for i, child := range children {
+				assert.Equal(t, level+1, child.Level())
+				assert.Equal(t, id, child.Parent(level))
+				if i > 0 {
+					assert.True(t, children[i-1] < child)
+				}
+			}
+		}
+	}
+
+	assert.False(t, CellID(0).IsValid())
+	assert.False(t, CellID(2).IsValid())
+	assert.Equal(t, "0/1000000000000000", CellIDOf(waterloo, 0).String())
+}
+
+func TestCellIDHilbertCurve(t *testing.T) {
+	level := uint(4)
+	n := uint64(1) << level
+
+	for position := uint64(0); position < n*n; position++ {
+		x, y := hilbertCoords(position, level)
+		assert.Equal(t, position, hilbertPosition(x, y, level))
+
+		// consecutive cells along the curve are adjacent
+		if position > 0 {
+			prevX, prevY := hilbertCoords(position-1, level)
+			assert.Equal(t, uint64(1), diff(x, prevX)+diff(y, prevY))
+		}
+	}
+}
+
+func diff(a, b uint64) uint64 {
+	if a > b {
+		return a - b
+	}
+	return b - a
+}
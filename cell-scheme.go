@@ -0,0 +1,375 @@
+package geoindex
+
+import (
+	"math"
+)
+
+// CellScheme splits the earth surface in cells. The indexes store their data per cell, so the scheme decides
+// the shape and the size of the cells. The schemes are a closed set: a CellScheme is created by NewGridScheme,
+// NewGeohashScheme, NewHexScheme or NewHilbertScheme and passed to the Scheme constructors of the indexes.
+type CellScheme struct {
+	scheme cellScheme
+}
+
+// Center returns the center of the cell that contains point.
+func (s CellScheme) Center(point Point) Point {
+	c := s.scheme.cellOf(point)
+	if shaped, ok := s.scheme.(shapedScheme); ok {
+		return shaped.center(c)
+	}
+
+	topLeft, bottomRight := s.scheme.bounds(c)
+	return &GeoPoint{"", (topLeft.Lat() + bottomRight.Lat()) / 2, (topLeft.Lon() + bottomRight.Lon()) / 2}
+}
+
+// Boundary returns the corners of the cell that contains point clockwise, the four corners of a rectangular cell
+// starting from the top left and the six corners of a hexagon starting from the top.
+func (s CellScheme) Boundary(point Point) []Point {
+	c := s.scheme.cellOf(point)
+	if shaped, ok := s.scheme.(shapedScheme); ok {
+		return shaped.boundary(c)
+	}
+
+	topLeft, bottomRight := s.scheme.bounds(c)
+	return []Point{
+		topLeft,
+		&GeoPoint{"", topLeft.Lat(), bottomRight.Lon()},
+		bottomRight,
+		&GeoPoint{"", bottomRight.Lat(), topLeft.Lon()},
+	}
+}
+
+// The cells of a CellScheme. The implementations are the unexported schemes of this package.
+type cellScheme interface {
+	// cellOf returns the cell that contains point.
+	cellOf(point Point) cell
+
+	// neighbours returns the cells that touch c.
+	neighbours(c cell) []cell
+
+	// rangeCells returns the cells covering the rectangle between topLeft and bottomRight. The rectangle
+	// wraps around the antimeridian when the longitude of topLeft is greater than the longitude of bottomRight.
+	rangeCells(topLeft Point, bottomRight Point) []cellSpan
+
+	// aroundCells returns the cells covering the circle with radius distance centered at point.
+	aroundCells(point Point, distance Meters) []cellSpan
+
+	// bounds returns the top left and bottom right corners of c.
+	bounds(c cell) (topLeft Point, bottomRight Point)
+
+	// size returns the approximate side of a cell.
+	size() Meters
+}
+
+// A cellScheme whose cells are not the rectangles of their bounds.
+type shapedScheme interface {
+	cellScheme
+
+	// center returns the center of c.
+	center(c cell) Point
+
+	// boundary returns the corners of c clockwise.
+	boundary(c cell) []Point
+}
+
+// A cell of a cellScheme. The meaning of x and y depends on the scheme.
+type cell struct {
+	x int
+	y int
+}
+
+// A span of cells in a single row x, from column minY to column maxY inclusive.
+type cellSpan struct {
+	x    int
+	minY int
+	maxY int
+}
+
+// normalizeLat clamps lat to the -90 to 90 range.
+func normalizeLat(lat float64) float64 {
+	return math.Max(-90, math.Min(90, lat))
+}
+
+// normalizeLon wraps lon to the -180 to 180 range.
+func normalizeLon(lon float64) float64 {
+	if lon < -180 || lon > 180 {
+		lon = math.Mod(lon+180, 360)
+		if lon < 0 {
+			lon += 360
+		}
+		lon -= 180
+	}
+	return lon
+}
+
+// lonIntervals splits the longitude range from minLon to maxLon in intervals within -180 and 180. Ranges
+// crossing the antimeridian, either with minLon > maxLon or with values out of the -180 to 180 range, are split
+// in two.
+func lonIntervals(minLon, maxLon float64) [][2]float64 {
+	if minLon > maxLon {
+		maxLon += 360
+	}
+
+	if maxLon-minLon >= 360 {
+		return [][2]float64{{-180, 180}}
+	}
+
+	for minLon < -180 {
+		minLon += 360
+		maxLon += 360
+	}
+
+	for minLon >= 180 {
+		minLon -= 360
+		maxLon -= 360
+	}
+
+	if maxLon > 180 {
+		return [][2]float64{{minLon, 180}, {-180, maxLon - 360}}
+	}
+
+	return [][2]float64{{minLon, maxLon}}
+}
+
+// ringSpans returns the spans of cells that are covered by outer but not by inner. Inner must be within outer.
+func ringSpans(outer []cellSpan, inner []cellSpan) []cellSpan {
+	innerRows := make(map[int][]cellSpan, len(inner))
+	for _, span := range inner {
+		innerRows[span.x] = append(innerRows[span.x], span)
+	}
+
+	result := make([]cellSpan, 0, len(outer)*2)
+	for _, span := range outer {
+		remaining := []cellSpan{span}
+
+		for _, innerSpan := range innerRows[span.x] {
+			remaining = subtractSpan(remaining, innerSpan)
+		}
+
+		result = append(result, remaining...)
+	}
+
+	return result
+}
+
+func subtractSpan(spans []cellSpan, other cellSpan) []cellSpan {
+	result := make([]cellSpan, 0, len(spans)+1)
+
+	for _, span := range spans {
+		if other.maxY < span.minY || other.minY > span.maxY {
+			result = append(result, span)
+			continue
+		}
+
+		if span.minY < other.minY {
+			result = append(result, cellSpan{span.x, span.minY, other.minY - 1})
+		}
+
+		if span.maxY > other.maxY {
+			result = append(result, cellSpan{span.x, other.maxY + 1, span.maxY})
+		}
+	}
+
+	return result
+}
+
+var (
+	// Rows beyond polarLat are too narrow to be split in columns, so each of them is a single cell.
+	polarLat = 85.0
+)
+
+// The default scheme. The grid is split in rows (x) of fixed height. Each row is split in columns (y), the width
+// of a column in degrees depends on the latitude of the row, so that the cells are roughly resolution wide at any
+// latitude. Rows closer to the poles than polarLat have a single column.
+type gridScheme struct {
+	resolution Meters
+}
+
+// NewGridScheme creates the default cell scheme, a grid with cells of size resolution.
+func NewGridScheme(resolution Meters) CellScheme {
+	return CellScheme{newGridScheme(resolution)}
+}
+
+func newGridScheme(resolution Meters) *gridScheme {
+	return &gridScheme{resolution}
+}
+
+func (g *gridScheme) cellOf(point Point) cell {
+	return cellOf(point, g.resolution)
+}
+
+func (g *gridScheme) neighbours(c cell) []cell {
+	topLeft, bottomRight := g.bounds(c)
+	return touchingCells(g, c, topLeft, bottomRight)
+}
+
+func (g *gridScheme) rangeCells(topLeft Point, bottomRight Point) []cellSpan {
+	return boxSpans(g, bottomRight.Lat(), topLeft.Lat(), topLeft.Lon(), bottomRight.Lon())
+}
+
+func (g *gridScheme) aroundCells(point Point, distance Meters) []cellSpan {
+	return aroundSpans(g, point, distance)
+}
+
+func (g *gridScheme) bounds(c cell) (topLeft Point, bottomRight Point) {
+	height := float64(g.resolution / latDegreeLength)
+	bottom := normalizeLat(minLat + float64(c.x)*height)
+	top := normalizeLat(minLat + float64(c.x+1)*height)
+
+	lat := rowLat(c.x, g.resolution)
+	if math.Abs(lat) > polarLat {
+		return &GeoPoint{"", top, -180}, &GeoPoint{"", bottom, 180}
+	}
+
+	width := float64(g.resolution / lonLength.get(lat))
+	left := minLon + float64(c.y)*width
+	right := math.Min(180, minLon+float64(c.y+1)*width)
+
+	return &GeoPoint{"", top, left}, &GeoPoint{"", bottom, right}
+}
+
+func (g *gridScheme) size() Meters {
+	return g.resolution
+}
+
+func rowOf(lat float64, resolution Meters) int {
+	return int((-minLat + normalizeLat(lat)) * float64(latDegreeLength) / float64(resolution))
+}
+
+// rowLat returns the latitude in the middle of row x.
+func rowLat(x int, resolution Meters) float64 {
+	return minLat + (float64(x)+0.5)*float64(resolution)/float64(latDegreeLength)
+}
+
+// columnOf returns the column of lon in row x.
+func columnOf(x int, lon float64, resolution Meters) int {
+	lat := rowLat(x, resolution)
+	if math.Abs(lat) > polarLat {
+		return 0
+	}
+
+	return int((-minLon + normalizeLon(lon)) * float64(lonLength.get(lat)) / float64(resolution))
+}
+
+func cellOf(point Point, resolution Meters) cell {
+	x := rowOf(point.Lat(), resolution)
+	y := columnOf(x, point.Lon(), resolution)
+
+	return cell{x, y}
+}
+
+// A scheme that splits the lat/lon plane in rows of latitude and then each row in columns of longitude.
+type latLonGrid interface {
+	rowOf(lat float64) int
+	columnOf(x int, lon float64) int
+}
+
+func (g *gridScheme) rowOf(lat float64) int {
+	return rowOf(lat, g.resolution)
+}
+
+func (g *gridScheme) columnOf(x int, lon float64) int {
+	return columnOf(x, lon, g.resolution)
+}
+
+// boxSpans returns the spans of cells of grid covering the rectangle between lat and lon ranges. The lon range
+// wraps around the antimeridian when minLon > maxLon.
+func boxSpans(grid latLonGrid, minLat, maxLat, minLon, maxLon float64) []cellSpan {
+	minx := grid.rowOf(minLat)
+	maxx := grid.rowOf(maxLat)
+	intervals := lonIntervals(minLon, maxLon)
+
+	spans := make([]cellSpan, 0, (maxx-minx+1)*len(intervals))
+	for x := minx; x <= maxx; x++ {
+		rowStart := len(spans)
+
+		for _, interval := range intervals {
+			span := cellSpan{x, grid.columnOf(x, interval[0]), grid.columnOf(x, interval[1])}
+
+			// the two sides of a wrapped range can share a column when the cells are wide
+			if len(spans) > rowStart && spans[rowStart].minY <= span.maxY {
+				spans[rowStart].minY = span.minY
+				continue
+			}
+
+			spans = append(spans, span)
+		}
+	}
+
+	return spans
+}
+
+// touchingCells returns the cells of grid that touch cell c with bounds topLeft and bottomRight.
+func touchingCells(grid latLonGrid, c cell, topLeft Point, bottomRight Point) []cell {
+	// the cells covering the bounds of c, a tiny bit extended
+	epsilon := 1e-9
+	spans := boxSpans(grid, bottomRight.Lat()-epsilon, topLeft.Lat()+epsilon, topLeft.Lon()-epsilon, bottomRight.Lon()+epsilon)
+
+	result := make([]cell, 0, 8)
+	for _, span := range spans {
+		for y := span.minY; y <= span.maxY; y++ {
+			if (cell{span.x, y}) != c {
+				result = append(result, cell{span.x, y})
+			}
+		}
+	}
+
+	return result
+}
+
+// aroundBox returns the lat and lon ranges of the smallest rectangle containing the circle with radius distance
+// centered at point. When the circle reaches a pole the rectangle covers all longitudes, so that points across the
+// pole are included.
+func aroundBox(point Point, distance Meters) (minLat, maxLat, minLon, maxLon float64) {
+	lat := normalizeLat(point.Lat())
+	angle := float64(distance / earthRadius)
+	dLat := toDegrees(angle)
+	minLat = lat - dLat
+	maxLat = lat + dLat
+
+	if maxLat >= 90 || minLat <= -90 {
+		return minLat, maxLat, -180, 180
+	}
+
+	// The widest longitude span of a circle with radius distance is not at the latitude of its center, but
+	// at the points where the meridians touch the circle.
+	sinAngle := math.Sin(angle)
+	cosLat := math.Cos(toRadians(lat))
+	if sinAngle >= cosLat {
+		return minLat, maxLat, -180, 180
+	}
+
+	dLon := toDegrees(math.Asin(sinAngle / cosLat))
+
+	return minLat, maxLat, point.Lon() - dLon, point.Lon() + dLon
+}
+
+// aroundRectBox returns the smallest rectangle containing all the circles with radius distance centered within the
+// rectangle between topLeft and bottomRight.
+func aroundRectBox(topLeft Point, bottomRight Point, distance Meters) (minLat, maxLat, minLon, maxLon float64) {
+	// the circles are widest at the latitude closest to a pole
+	polewardLat := topLeft.Lat()
+	if math.Abs(bottomRight.Lat()) > math.Abs(polewardLat) {
+		polewardLat = bottomRight.Lat()
+	}
+
+	_, _, west, east := aroundBox(&GeoPoint{"", polewardLat, 0}, distance)
+	dLat := toDegrees(float64(distance / earthRadius))
+
+	minLat = bottomRight.Lat() - dLat
+	maxLat = topLeft.Lat() + dLat
+	minLon = topLeft.Lon() + west
+	maxLon = bottomRight.Lon() + east
+
+	if maxLon-minLon >= 360 {
+		return minLat, maxLat, -180, 180
+	}
+
+	return minLat, maxLat, minLon, maxLon
+}
+
+// aroundSpans returns the spans of cells of grid covering the circle with radius distance centered at point.
+func aroundSpans(grid latLonGrid, point Point, distance Meters) []cellSpan {
+	minLat, maxLat, minLon, maxLon := aroundBox(point, distance)
+	return boxSpans(grid, minLat, maxLat, minLon, maxLon)
+}
@@ -0,0 +1,88 @@
+package geoindex
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCellWidthFollowsLatitude(t *testing.T) {
+	resolution := Km(0.5)
+
+	for _, lat := range []float64{0.0, 51.5, 60.0, 70.0} {
+		x := rowOf(lat, resolution)
+		columns := columnOf(x, 180.0, resolution) - columnOf(x, -180.0, resolution)
+		circumference := 2 * math.Pi * float64(earthRadius) * math.Cos(toRadians(rowLat(x, resolution)))
+
+		assert.InEpsilon(t, circumference/float64(resolution), float64(columns), 0.001)
+	}
+}
+
+func TestLonIntervals(t *testing.T) {
+	assert.Equal(t, [][2]float64{{-10, 10}}, lonIntervals(-10, 10))
+	assert.Equal(t, [][2]float64{{170, 180}, {-180, -170}}, lonIntervals(170, -170))
+	assert.Equal(t, [][2]float64{{170, 180}, {-180, -170}}, lonIntervals(170, 190))
+	assert.Equal(t, [][2]float64{{170, 180}, {-180, -170}}, lonIntervals(-190, -170))
+	assert.Equal(t, [][2]float64{{-180, 180}}, lonIntervals(-200, 200))
+}
+
+func TestGridSchemeBounds(t *testing.T) {
+	scheme := newGridScheme(Km(0.5))
+
+	for _, point := range []Point{waterloo, reykjavik, ankara, &GeoPoint{"", -33.87, 151.21}} {
+		c := scheme.cellOf(point)
+		topLeft, bottomRight := scheme.bounds(c)
+
+		assert.True(t, between(point.Lat(), bottomRight.Lat(), topLeft.Lat()))
+		assert.True(t, between(point.Lon(), topLeft.Lon(), bottomRight.Lon()))
+		assert.InDelta(t, 500.0, float64(distance(topLeft, &GeoPoint{"", topLeft.Lat(), bottomRight.Lon()})), 5.0)
+		assert.InDelta(t, 500.0, float64(distance(topLeft, &GeoPoint{"", bottomRight.Lat(), topLeft.Lon()})), 5.0)
+
+		center := &GeoPoint{"", (topLeft.Lat() + bottomRight.Lat()) / 2, (topLeft.Lon() + bottomRight.Lon()) / 2}
+		assert.Equal(t, c, scheme.cellOf(center))
+	}
+
+	topLeft, bottomRight := scheme.bounds(scheme.cellOf(&GeoPoint{"", 89.0, 10.0}))
+	assert.Equal(t, -180.0, topLeft.Lon())
+	assert.Equal(t, 180.0, bottomRight.Lon())
+}
+
+func TestCellSchemeBoundary(t *testing.T) {
+	grid := NewGridScheme(Km(0.5))
+	corners := grid.Boundary(waterloo)
+	assert.Equal(t, 4, len(corners))
+	assert.True(t, corners[0].Lat() > corners[3].Lat() && corners[0].Lon() < corners[1].Lon())
+	assert.Equal(t, CellOf(waterloo, Km(0.5)).Center(), grid.Center(waterloo))
+
+	hex := NewHexScheme(Km(0.5), 51.5)
+	corners = hex.Boundary(waterloo)
+	assert.Equal(t, 6, len(corners))
+	for _, corner := range corners {
+		assert.InDelta(t, 500.0, float64(distance(hex.Center(waterloo), corner)), 5.0)
+	}
+}
+
+func TestGridSchemeNeighbours(t *testing.T) {
+	scheme := newGridScheme(Km(0.5))
+
+	c := scheme.cellOf(waterloo)
+	neighbours := scheme.neighbours(c)
+	// rows are not aligned, so a cell touches two or three cells in the rows above and below
+	assert.True(t, len(neighbours) >= 6 && len(neighbours) <= 8)
+
+	topLeft, bottomRight := scheme.bounds(c)
+	for _, neighbour := range neighbours {
+		assert.NotEqual(t, c, neighbour)
+		assert.True(t, neighbour.x >= c.x-1 && neighbour.x <= c.x+1)
+
+		neighbourTopLeft, neighbourBottomRight := scheme.bounds(neighbour)
+		assert.True(t, neighbourTopLeft.Lon() <= bottomRight.Lon()+1e-9)
+		assert.True(t, neighbourBottomRight.Lon() >= topLeft.Lon()-1e-9)
+	}
+
+	// the first and the last column of a row are neighbours
+	west := scheme.cellOf(&GeoPoint{"", 0.0, -179.999})
+	east := scheme.cellOf(&GeoPoint{"", 0.0, 179.999})
+	assert.Contains(t, scheme.neighbours(west), east)
+}
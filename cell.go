@@ -0,0 +1,104 @@
+package geoindex
+
+import (
+	"fmt"
+)
+
+// Cell is a cell of the default grid, the same cells that the indexes created with a resolution use.
+type Cell struct {
+	cell
+	resolution Meters
+}
+
+// CellOf returns the cell of the grid with cells of size resolution that contains point.
+func CellOf(point Point, resolution Meters) Cell {
+	return Cell{cellOf(point, resolution), resolution}
+}
+
+// CellFromID returns the cell of the grid with cells of size resolution with id.
+func CellFromID(id uint64, resolution Meters) Cell {
+	return Cell{cell{int(id >> 32), int(uint32(id))}, resolution}
+}
+
+// ParseCell parses a cell from its string representation.
+func ParseCell(value string) (Cell, error) {
+	var resolution float64
+	var x, y int
+
+	if _, err := fmt.Sscanf(value, "%gm/%d/%d", &resolution, &x, &y); err != nil {
+		return Cell{}, fmt.Errorf("invalid cell %q: %v", value, err)
+	}
+
+	if resolution <= 0 || x < 0 || y < 0 {
+		return Cell{}, fmt.Errorf("invalid cell %q", value)
+	}
+
+	return Cell{cell{x, y}, Meters(resolution)}, nil
+}
+
+// Resolution returns the size of the cells of the grid of c.
+func (c Cell) Resolution() Meters {
+	return c.resolution
+}
+
+// Row returns the row of c, rows go from south to north.
+func (c Cell) Row() int {
+	return c.x
+}
+
+// Column returns the column of c within its row, columns go from west to east.
+func (c Cell) Column() int {
+	return c.y
+}
+
+// ID returns an id of c, which is unique among the cells with the same resolution.
+func (c Cell) ID() uint64 {
+	return uint64(c.x)<<32 | uint64(uint32(c.y))
+}
+
+// String returns the resolution, the row and the column of c, which can be parsed back with ParseCell.
+func (c Cell) String() string {
+	return fmt.Sprintf("%gm/%d/%d", float64(c.resolution), c.x, c.y)
+}
+
+// Bounds returns the top left and bottom right corners of c.
+func (c Cell) Bounds() (topLeft Point, bottomRight Point) {
+	return newGridScheme(c.resolution).bounds(c.cell)
+}
+
+// Center returns the center of c.
+func (c Cell) Center() Point {
+	topLeft, bottomRight := c.Bounds()
+	return &GeoPoint{"", (topLeft.Lat() + bottomRight.Lat()) / 2, (topLeft.Lon() + bottomRight.Lon()) / 2}
+}
+
+// Neighbours returns the cells ring steps away from c, where each step moves to a cell that touches the
+// previous one. Ring 0 is c itself and ring 1 are the cells that touch c.
+func (c Cell) Neighbours(ring int) []Cell {
+	scheme := newGridScheme(c.resolution)
+
+	visited := map[cell]bool{c.cell: true}
+	current := []cell{c.cell}
+
+	for i := 0; i < ring; i++ {
+		next := make([]cell, 0, len(current)+8)
+
+		for _, from := range current {
+			for _, neighbour := range scheme.neighbours(from) {
+				if !visited[neighbour] {
+					visited[neighbour] = true
+					next = append(next, neighbour)
+				}
+			}
+		}
+
+		current = next
+	}
+
+	result := make([]Cell, len(current))
+	for i, neighbour := range current {
+		result[i] = Cell{neighbour, c.resolution}
+	}
+
+	return result
+}
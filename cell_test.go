@@ -0,0 +1,62 @@
+package geoindex
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCellOf(t *testing.T) {
+	index := NewPointsIndex(Km(0.5))
+	index.Add(charring)
+
+	c := CellOf(charring, Km(0.5))
+	assert.Equal(t, Km(0.5), c.Resolution())
+	assert.Equal(t, cellOf(charring, Km(0.5)), c.cell)
+	assert.Contains(t, index.index.index, c.cell)
+
+	topLeft, bottomRight := c.Bounds()
+	assert.True(t, between(charring.Lat(), bottomRight.Lat(), topLeft.Lat()))
+	assert.True(t, between(charring.Lon(), topLeft.Lon(), bottomRight.Lon()))
+	assert.Equal(t, c, CellOf(c.Center(), Km(0.5)))
+}
+
+func TestCellIds(t *testing.T) {
+	for _, point := range []Point{charring, reykjavik, ankara, suva, lakeba} {
+		c := CellOf(point, Km(0.5))
+
+		assert.Equal(t, c, CellFromID(c.ID(), Km(0.5)))
+
+		parsed, err := ParseCell(c.String())
+		assert.Nil(t, err)
+		assert.Equal(t, c, parsed)
+	}
+
+	assert.Equal(t, "500m/31413/24903", CellOf(waterloo, Km(0.5)).String())
+
+	for _, value := range []string{"", "500m/1", "abc", "-1m/1/1", "500m/-1/1"} {
+		_, err := ParseCell(value)
+		assert.NotNil(t, err)
+	}
+}
+
+func TestCellNeighbours(t *testing.T) {
+	c := CellOf(waterloo, Km(0.5))
+
+	assert.Equal(t, []Cell{c}, c.Neighbours(0))
+
+	ring1 := c.Neighbours(1)
+	scheme := newGridScheme(Km(0.5))
+	assert.Equal(t, len(scheme.neighbours(c.cell)), len(ring1))
+	for _, neighbour := range ring1 {
+		assert.Contains(t, scheme.neighbours(c.cell), neighbour.cell)
+	}
+
+	ring2 := c.Neighbours(2)
+	assert.True(t, len(ring2) >= 12 && len(ring2) <= 20)
+	for _, neighbour := range ring2 {
+		assert.NotContains(t, ring1, neighbour)
+		assert.NotEqual(t, c, neighbour)
+		assert.True(t, neighbour.Row() >= c.Row()-2 && neighbour.Row() <= c.Row()+2)
+	}
+}
@@ -14,10 +14,16 @@ var (
 // NewClusteringIndex creates index that clusters the points at three levels with cell size 0.5, 5 and 500km.
 // Useful for creating maps.
 func NewClusteringIndex() *ClusteringIndex {
+	return NewSchemeClusteringIndex(NewGridScheme)
+}
+
+// NewSchemeClusteringIndex creates index that clusters the points at three levels with cells created by newScheme
+// with size 0.5, 5 and 500km.
+func NewSchemeClusteringIndex(newScheme func(resolution Meters) CellScheme) *ClusteringIndex {
 	index := &ClusteringIndex{}
-	index.streetLevel = NewPointsIndex(Km(0.5))
-	index.cityLevel = NewCountIndex(Km(10))
-	index.worldLevel = NewCountIndex(Km(500))
+	index.streetLevel = NewSchemePointsIndex(newScheme(Km(0.5)))
+	index.cityLevel = NewSchemeCountIndex(newScheme(Km(10)))
+	index.worldLevel = NewSchemeCountIndex(newScheme(Km(500)))
 
 	return index
 }
@@ -25,10 +31,16 @@ func NewClusteringIndex() *ClusteringIndex {
 // NewExpiringClusteringIndex creates index that clusters the points at three levels with cell size 0.5, 5 and 500km and
 // expires them after expiration minutes.
 func NewExpiringClusteringIndex(expiration Minutes) *ClusteringIndex {
+	return NewExpiringSchemeClusteringIndex(NewGridScheme, expiration)
+}
+
+// NewExpiringSchemeClusteringIndex creates index that clusters the points at three levels with cells created by
+// newScheme with size 0.5, 5 and 500km and expires them after expiration minutes.
+func NewExpiringSchemeClusteringIndex(newScheme func(resolution Meters) CellScheme, expiration Minutes) *ClusteringIndex {
 	index := &ClusteringIndex{}
-	index.streetLevel = NewExpiringPointsIndex(Km(0.5), expiration)
-	index.cityLevel = NewExpiringCountIndex(Km(10), expiration)
-	index.worldLevel = NewExpiringCountIndex(Km(500), expiration)
+	index.streetLevel = NewExpiringSchemePointsIndex(newScheme(Km(0.5)), expiration)
+	index.cityLevel = NewExpiringSchemeCountIndex(newScheme(Km(10)), expiration)
+	index.worldLevel = NewExpiringSchemeCountIndex(newScheme(Km(500)), expiration)
 
 	return index
 }
@@ -20,20 +20,30 @@ func (p *CountPoint) String() string {
 
 // NewCountIndex creates an index which counts the points in each cell.
 func NewCountIndex(resolution Meters) *CountIndex {
+	return NewSchemeCountIndex(NewGridScheme(resolution))
+}
+
+// NewSchemeCountIndex creates an index which counts the points in each cell of scheme.
+func NewSchemeCountIndex(scheme CellScheme) *CountIndex {
 	newCounter := func() interface{} {
 		return &singleValueAccumulatingCounter{}
 	}
 
-	return &CountIndex{newGeoIndex(resolution, newCounter), make(map[string]Point)}
+	return &CountIndex{newSchemeGeoIndex(scheme.scheme, newCounter), make(map[string]Point)}
 }
 
 // NewExpiringCountIndex creates an index, which maintains an expiring counter for each cell.
 func NewExpiringCountIndex(resolution Meters, expiration Minutes) *CountIndex {
+	return NewExpiringSchemeCountIndex(NewGridScheme(resolution), expiration)
+}
+
+// NewExpiringSchemeCountIndex creates an index, which maintains an expiring counter for each cell of scheme.
+func NewExpiringSchemeCountIndex(scheme CellScheme, expiration Minutes) *CountIndex {
 	newExpiringCounter := func() interface{} {
 		return newExpiringCounter(expiration)
 	}
 
-	return &CountIndex{newGeoIndex(resolution, newExpiringCounter), make(map[string]Point)}
+	return &CountIndex{newSchemeGeoIndex(scheme.scheme, newExpiringCounter), make(map[string]Point)}
 }
 
 func (index *CountIndex) Clone() *CountIndex {
@@ -68,16 +78,65 @@ func (countIndex *CountIndex) Remove(id string) {
 
 // Range returns the counters within some lat, lng range.
 func (countIndex *CountIndex) Range(topLeft Point, bottomRight Point) []Point {
-	counters := countIndex.index.Range(topLeft, bottomRight)
+	return countIndex.AppendRange(make([]Point, 0), topLeft, bottomRight)
+}
+
+// AppendRange appends the counters within some lat, lng range to dst and returns the extended slice.
+func (countIndex *CountIndex) AppendRange(dst []Point, topLeft Point, bottomRight Point) []Point {
+	countIndex.RangeFunc(topLeft, bottomRight, func(point Point) bool {
+		dst = append(dst, point)
+		return true
+	})
+
+	return dst
+}
+
+// RangeFunc calls fn with each counter within some lat, lng range, without collecting the counters, until fn
+// returns false.
+func (countIndex *CountIndex) RangeFunc(topLeft Point, bottomRight Point, fn func(point Point) bool) {
+	countIndex.index.VisitRange(topLeft, bottomRight, func(c interface{}) bool {
+		point := c.(counter).Point()
+		return point == nil || fn(point)
+	})
+}
+
+// WithinPolygon returns the counters of the cells within polygon. The counters of cells crossed by the edges of
+// the polygon are returned if their position is within the polygon.
+func (countIndex *CountIndex) WithinPolygon(polygon *Polygon) []Point {
+	inside, boundary := countIndex.index.Polygon(polygon)
 
 	points := make([]Point, 0)
 
-	for _, c := range counters {
+	for _, c := range inside {
 		if c.(counter).Point() != nil {
 			points = append(points, c.(counter).Point())
 		}
 	}
 
+	for _, c := range boundary {
+		if point := c.(counter).Point(); point != nil && polygon.Contains(point) {
+			points = append(points, point)
+		}
+	}
+
+	return points
+}
+
+// PointsBetween returns the counters of the cells between minDistance and maxDistance away from point, whose
+// position is at least minDistance and less than maxDistance away from point. The cells entirely within
+// minDistance are skipped.
+func (countIndex *CountIndex) PointsBetween(point Point, minDistance Meters, maxDistance Meters) []Point {
+	points := make([]Point, 0)
+
+	countIndex.index.VisitBetween(point, minDistance, maxDistance, func(c interface{}) bool {
+		if counter := c.(counter).Point(); counter != nil {
+			if dist := Distance(point, counter); dist >= minDistance && dist < maxDistance {
+				points = append(points, counter)
+			}
+		}
+		return true
+	})
+
 	return points
 }
 
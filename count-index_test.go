@@ -35,14 +35,65 @@ func TestCountIndex(t *testing.T) {
 	counters := countIndex.Range(oxford, embankment)
 	expected := []Point{
 		&CountPoint{&GeoPoint{"", 51.500776, -0.158290}, 7},
-		&CountPoint{&GeoPoint{"", 51.504882, -0.124143}, 11},
-		&CountPoint{&GeoPoint{"", 51.522122, -0.157587}, 12},
-		&CountPoint{&GeoPoint{"", 51.523935, -0.129213}, 10},
+		&CountPoint{&GeoPoint{"", 51.503577, -0.118625}, 15},
+		&CountPoint{&GeoPoint{"", 51.523064, -0.156137}, 13},
+		&CountPoint{&GeoPoint{"", 51.522537, -0.119915}, 13},
 	}
 
 	assert.True(t, pointsEqual(counters, expected))
 }
 
+func TestCountIndexRangeFunc(t *testing.T) {
+	countIndex := NewCountIndex(Km(3.0))
+
+	for _, station := range tubeStations() {
+		countIndex.Add(station)
+	}
+
+	counters := make([]Point, 0)
+	countIndex.RangeFunc(oxford, embankment, func(counter Point) bool {
+		counters = append(counters, counter)
+		return true
+	})
+	assert.True(t, pointsEqual(countIndex.Range(oxford, embankment), counters))
+
+	visited := 0
+	countIndex.RangeFunc(oxford, embankment, func(counter Point) bool {
+		visited++
+		return false
+	})
+	assert.Equal(t, 1, visited)
+
+	dst := countIndex.AppendRange([]Point{oxford}, oxford, embankment)
+	assert.Equal(t, 5, len(dst))
+	assert.Equal(t, oxford, dst[0])
+}
+
+func TestCountIndexPointsBetween(t *testing.T) {
+	countIndex := NewCountIndex(Km(0.5))
+
+	for _, station := range tubeStations() {
+		countIndex.Add(station)
+	}
+
+	between := countIndex.PointsBetween(charring, Km(1), Km(3))
+	assert.NotEmpty(t, between)
+
+	within := countIndex.Range(&GeoPoint{"", 51.55, -0.18}, &GeoPoint{"", 51.47, -0.07})
+	count := 0
+	for _, counter := range within {
+		if d := Distance(charring, counter); d >= Km(1) && d < Km(3) {
+			assert.Contains(t, between, counter)
+			count++
+		}
+	}
+	assert.Equal(t, count, len(between))
+
+	for _, counter := range between {
+		assert.True(t, Distance(charring, counter) >= Km(1))
+	}
+}
+
 func TestExpiringCountIndex(t *testing.T) {
 	countIndex := NewExpiringCountIndex(Km(0.5), Minutes(1))
 
@@ -78,3 +129,18 @@ func BenchmarkExpiringCountIndexAdd(b *testing.B) {
 func BenchmarkExpiringCountIndexRange(b *testing.B) {
 	bench(b).CentralLondonRange(NewExpiringCountIndex(Km(0.5), Minutes(15)))
 }
+
+func TestCountIndexAntimeridianRange(t *testing.T) {
+	countIndex := NewCountIndex(Km(10))
+	countIndex.Add(&GeoPoint{"west", -17.0, 179.99})
+	countIndex.Add(&GeoPoint{"east", -17.0, -179.99})
+	countIndex.Add(&GeoPoint{"far", -17.0, 170.0})
+
+	counters := countIndex.Range(&GeoPoint{"", -16.5, 179.5}, &GeoPoint{"", -17.5, -179.5})
+
+	count := 0
+	for _, counter := range toCountPoints(counters) {
+		count += counter.Count.(int)
+	}
+	assert.Equal(t, 2, count)
+}
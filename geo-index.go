@@ -1,10 +1,15 @@
 package geoindex
 
+import (
+	"math"
+	"sort"
+	"sync"
+)
+
 var (
 	minLon          = -180.0
 	minLat          = -90.0
 	latDegreeLength = Km(111.0)
-	lonDegreeLength = Km(85.0)
 )
 
 type Meters float64
@@ -17,34 +22,40 @@ func Meter(meters float64) Meters {
 	return Meters(meters)
 }
 
-type cell struct {
-	x int
-	y int
-}
-
-func cellOf(point Point, resolution Meters) cell {
-	x := int((-minLat + point.Lat()) * float64(latDegreeLength) / float64(resolution))
-	y := int((-minLon + point.Lon()) * float64(lonDegreeLength) / float64(resolution))
-
-	return cell{x, y}
-}
-
 type geoIndex struct {
-	resolution Meters
-	index      map[cell]interface{}
-	newEntry   func() interface{}
+	scheme   cellScheme
+	index    map[cell]interface{}
+	newEntry func() interface{}
+	// the sorted ids of the cells in index, only maintained for ordered schemes. The ids of new cells are pending
+	// until the next query sorts them in, so that adding many cells is not quadratic.
+	ids     []CellID
+	pending []CellID
+	idsLock sync.Mutex
 }
 
-// Creates new geo index with resolution a function that returns a new entry that is stored in each cell.
+// Creates new geo index with a grid of cells of size resolution and a function that returns a new entry that is
+// stored in each cell.
 func newGeoIndex(resolution Meters, newEntry func() interface{}) *geoIndex {
-	return &geoIndex{resolution, make(map[cell]interface{}), newEntry}
+	return newSchemeGeoIndex(newGridScheme(resolution), newEntry)
+}
+
+// Creates new geo index with cells defined by scheme and a function that returns a new entry that is stored in
+// each cell.
+func newSchemeGeoIndex(scheme cellScheme, newEntry func() interface{}) *geoIndex {
+	return &geoIndex{scheme: scheme, index: make(map[cell]interface{}), newEntry: newEntry}
 }
 
 func (i *geoIndex) Clone() *geoIndex {
+	scheme := i.scheme
+	if adaptive, ok := scheme.(adaptiveScheme); ok {
+		scheme = adaptive.clone()
+	}
+
 	clone := &geoIndex{
-		resolution: i.resolution,
-		index:      make(map[cell]interface{}, len(i.index)),
-		newEntry:   i.newEntry,
+		scheme:   scheme,
+		index:    make(map[cell]interface{}, len(i.index)),
+		newEntry: i.newEntry,
+		ids:      append([]CellID(nil), i.sortedIDs()...),
 	}
 	for k, v := range i.index {
 		set, ok := v.(set)
@@ -59,10 +70,14 @@ func (i *geoIndex) Clone() *geoIndex {
 
 // AddEntryAt adds an entry if missing, returns the entry at specific position.
 func (geoIndex *geoIndex) AddEntryAt(point Point) interface{} {
-	square := cellOf(point, geoIndex.resolution)
+	square := geoIndex.scheme.cellOf(point)
 
 	if _, ok := geoIndex.index[square]; !ok {
 		geoIndex.index[square] = geoIndex.newEntry()
+
+		if ordered, ok := geoIndex.scheme.(orderedScheme); ok {
+			geoIndex.addID(ordered.cellID(square))
+		}
 	}
 
 	return geoIndex.index[square]
@@ -70,7 +85,7 @@ func (geoIndex *geoIndex) AddEntryAt(point Point) interface{} {
 
 // GetEntryAt gets an entry from the geoindex, if missing returns an empty entry without changing the index.
 func (geoIndex *geoIndex) GetEntryAt(point Point) interface{} {
-	square := cellOf(point, geoIndex.resolution)
+	square := geoIndex.scheme.cellOf(point)
 
 	entries, ok := geoIndex.index[square]
 	if !ok {
@@ -80,36 +95,389 @@ func (geoIndex *geoIndex) GetEntryAt(point Point) interface{} {
 	return entries
 }
 
-// Range returns the index entries within lat, lng range.
-func (geoIndex *geoIndex) Range(topLeft Point, bottomRight Point) []interface{} {
-	topLeftIndex := cellOf(topLeft, geoIndex.resolution)
-	bottomRightIndex := cellOf(bottomRight, geoIndex.resolution)
+// Added lets adaptive schemes update their cells after a point was added to the entry at point.
+func (geoIndex *geoIndex) Added(point Point) {
+	if adaptive, ok := geoIndex.scheme.(adaptiveScheme); ok {
+		adaptive.added(geoIndex, adaptive.cellOf(point))
+	}
+}
 
-	return geoIndex.get(bottomRightIndex.x, topLeftIndex.x, topLeftIndex.y, bottomRightIndex.y)
+// Removed lets adaptive schemes update their cells after a point was removed from the entry at point.
+func (geoIndex *geoIndex) Removed(point Point) {
+	if adaptive, ok := geoIndex.scheme.(adaptiveScheme); ok {
+		adaptive.removed(geoIndex, adaptive.cellOf(point))
+	}
 }
 
-func (geoIndex *geoIndex) get(minx int, maxx int, miny int, maxy int) []interface{} {
+// Range returns the index entries within lat, lng range. The range wraps around the antimeridian when the
+// longitude of topLeft is greater than the longitude of bottomRight.
+func (geoIndex *geoIndex) Range(topLeft Point, bottomRight Point) []interface{} {
 	entries := make([]interface{}, 0, 0)
 
-	for x := minx; x <= maxx; x++ {
-		for y := miny; y <= maxy; y++ {
-			if indexEntry, ok := geoIndex.index[cell{x, y}]; ok {
-				entries = append(entries, indexEntry)
+	geoIndex.VisitRange(topLeft, bottomRight, func(entry interface{}) bool {
+		entries = append(entries, entry)
+		return true
+	})
+
+	return entries
+}
+
+// VisitRange calls visitor with the index entries within lat, lng range, like Range, until visitor returns false.
+func (geoIndex *geoIndex) VisitRange(topLeft Point, bottomRight Point, visitor func(entry interface{}) bool) {
+	geoIndex.visitRange(topLeft, bottomRight, func(_ cell, entry interface{}) bool {
+		return visitor(entry)
+	})
+}
+
+// VisitRangeInside is like VisitRange, but also tells visitor whether the cell of each entry is entirely within
+// the range.
+func (geoIndex *geoIndex) VisitRangeInside(topLeft Point, bottomRight Point, visitor func(entry interface{}, inside bool) bool) {
+	geoIndex.visitRange(topLeft, bottomRight, func(c cell, entry interface{}) bool {
+		return visitor(entry, geoIndex.cellInRange(c, topLeft, bottomRight))
+	})
+}
+
+func (geoIndex *geoIndex) visitRange(topLeft Point, bottomRight Point, visitor func(c cell, entry interface{}) bool) {
+	if ordered, ok := geoIndex.scheme.(orderedScheme); ok {
+		// the cover can extend beyond the range, so the cells outside of it are skipped
+		geoIndex.visitCover(ordered, ordered.rangeCover(topLeft, bottomRight), func(c cell, entry interface{}) bool {
+			return !geoIndex.cellTouchesRange(c, topLeft, bottomRight) || visitor(c, entry)
+		})
+		return
+	}
+
+	geoIndex.visit(geoIndex.scheme.rangeCells(topLeft, bottomRight), visitor)
+}
+
+// cellTouchesRange returns true if some of c is within lat, lng range.
+func (geoIndex *geoIndex) cellTouchesRange(c cell, topLeft Point, bottomRight Point) bool {
+	cellTopLeft, cellBottomRight := geoIndex.scheme.bounds(c)
+	if cellBottomRight.Lat() > topLeft.Lat() || cellTopLeft.Lat() < bottomRight.Lat() {
+		return false
+	}
+
+	for _, interval := range lonIntervals(topLeft.Lon(), bottomRight.Lon()) {
+		if cellTopLeft.Lon() <= interval[1] && cellBottomRight.Lon() >= interval[0] {
+			return true
+		}
+	}
+
+	return false
+}
+
+// cellInRange returns true if all of c is within lat, lng range.
+func (geoIndex *geoIndex) cellInRange(c cell, topLeft Point, bottomRight Point) bool {
+	cellTopLeft, cellBottomRight := geoIndex.scheme.bounds(c)
+	if cellBottomRight.Lat() < bottomRight.Lat() || cellTopLeft.Lat() > topLeft.Lat() {
+		return false
+	}
+
+	minLon, maxLon := normalizeLon(topLeft.Lon()), normalizeLon(bottomRight.Lon())
+	left, right := cellTopLeft.Lon(), cellBottomRight.Lon()
+
+	// a range that wraps around the antimeridian contains the cells on either side of it
+	if minLon > maxLon {
+		return left >= minLon || right <= maxLon
+	}
+	return left >= minLon && right <= maxLon
+}
+
+// Around returns the index entries in the cells covering the circle with radius distance centered at point.
+func (geoIndex *geoIndex) Around(point Point, distance Meters) []interface{} {
+	return geoIndex.get(geoIndex.scheme.aroundCells(point, distance))
+}
+
+// VisitAround calls visitor with the index entries in the cells covering the circle with radius distance centered
+// at point, until visitor returns false.
+func (geoIndex *geoIndex) VisitAround(point Point, distance Meters, visitor func(entry interface{}) bool) {
+	geoIndex.visit(geoIndex.scheme.aroundCells(point, distance), func(_ cell, entry interface{}) bool {
+		return visitor(entry)
+	})
+}
+
+// VisitAroundInside is like VisitAround, but also tells visitor whether the cell of each entry is entirely closer
+// than distance to point.
+func (geoIndex *geoIndex) VisitAroundInside(point Point, distance Meters, visitor func(entry interface{}, inside bool) bool) {
+	geoIndex.visit(geoIndex.scheme.aroundCells(point, distance), func(c cell, entry interface{}) bool {
+		return visitor(entry, geoIndex.cellWithin(c, point, distance))
+	})
+}
+
+// VisitBetween calls visitor with the index entries in the cells covering the circle with radius maxDistance
+// centered at point, except the cells that are entirely within minDistance of point, until visitor returns false.
+func (geoIndex *geoIndex) VisitBetween(point Point, minDistance Meters, maxDistance Meters, visitor func(entry interface{}) bool) {
+	geoIndex.visit(geoIndex.scheme.aroundCells(point, maxDistance), func(c cell, entry interface{}) bool {
+		return geoIndex.cellWithin(c, point, minDistance) || visitor(entry)
+	})
+}
+
+// cellWithin returns true if all of c is closer than distance to point. The farthest point of a rectangle that
+// spans less than 180 degrees of longitude from point is one of its corners.
+func (geoIndex *geoIndex) cellWithin(c cell, point Point, distance Meters) bool {
+	topLeft, bottomRight := geoIndex.scheme.bounds(c)
+	if bottomRight.Lon()-topLeft.Lon() >= 180 {
+		return false
+	}
+
+	for _, corner := range []Point{
+		topLeft,
+		bottomRight,
+		&GeoPoint{"", topLeft.Lat(), bottomRight.Lon()},
+		&GeoPoint{"", bottomRight.Lat(), topLeft.Lon()},
+	} {
+		if Distance(point, corner) >= distance {
+			return false
+		}
+	}
+
+	return true
+}
+
+// VisitExpanding calls visitor with the index entries in the cells around point in growing circles, nearest cells
+// first, until everything within maxDistance was visited. The first circle is the cell of point, after each circle
+// radius returns the radius of the next one given the radius searched so far, and the search stops when it is not
+// greater than searched.
+func (geoIndex *geoIndex) VisitExpanding(point Point, maxDistance Meters, visitor func(entry interface{}) bool, radius func(searched Meters) Meters) {
+	search := geoIndex.newCircleSearch(point, maxDistance)
+	for search.next(radius(search.searched), visitor) {
+	}
+}
+
+// A search of the cells around a point in growing circles, which is resumed by calling next.
+type circleSearch struct {
+	index       *geoIndex
+	point       Point
+	maxDistance Meters
+	started     bool
+	// the radius of the circle visited so far
+	searched Meters
+}
+
+func (geoIndex *geoIndex) newCircleSearch(point Point, maxDistance Meters) *circleSearch {
+	return &circleSearch{index: geoIndex, point: point, maxDistance: maxDistance}
+}
+
+// next calls visitor with the index entries in the cells covering the circle with radius, up to maxDistance, which
+// were not visited yet. The first call visits the cell of the point only. It returns false without visiting when
+// radius is not greater than the radius searched or everything within maxDistance was visited.
+func (search *circleSearch) next(radius Meters, visitor func(entry interface{}) bool) bool {
+	geoIndex := search.index
+
+	if !search.started {
+		search.started = true
+		geoIndex.visit(geoIndex.scheme.aroundCells(search.point, 0), func(_ cell, entry interface{}) bool {
+			return visitor(entry)
+		})
+		return true
+	}
+
+	if search.searched >= search.maxDistance || search.searched >= math.Pi*earthRadius || radius <= search.searched {
+		return false
+	}
+
+	radius = Meters(math.Min(float64(radius), float64(search.maxDistance)))
+	outer := geoIndex.scheme.aroundCells(search.point, radius)
+	inner := geoIndex.scheme.aroundCells(search.point, search.searched)
+	geoIndex.visit(ringSpans(outer, inner), func(_ cell, entry interface{}) bool {
+		return visitor(entry)
+	})
+	search.searched = radius
+
+	return true
+}
+
+// growRadius returns the radius of the next circle to search around a point after searching radius. The radius
+// doubles, so that sparse areas are searched in a few steps, but grows by at least a cell.
+func (geoIndex *geoIndex) growRadius(radius Meters) Meters {
+	return Meters(math.Max(float64(2*radius), float64(radius+geoIndex.scheme.size())))
+}
+
+// Corridor returns the index entries in the cells covering the area within width of the route through path.
+func (geoIndex *geoIndex) Corridor(path []Point, width Meters) []interface{} {
+	step := math.Max(float64(width), float64(geoIndex.scheme.size()))
+	visited := make(map[cell]bool)
+	spans := make([]cellSpan, 0)
+
+	// Cover each leg with circles around points at most step apart, so that every point within width of the leg
+	// is within width plus half a step of one of them.
+	routeLegs(path, func(a, b Point) {
+		length := float64(Distance(a, b))
+		steps := math.Max(1, math.Ceil(length/step))
+		radius := width + Meters(length/steps/2)
+
+		for i := 0.0; i <= steps; i++ {
+			for _, span := range geoIndex.scheme.aroundCells(intermediatePoint(a, b, i/steps), radius) {
+				for y := span.minY; y <= span.maxY; y++ {
+					if c := (cell{span.x, y}); !visited[c] {
+						visited[c] = true
+						spans = append(spans, cellSpan{span.x, y, y})
+					}
+				}
 			}
 		}
+	})
+
+	return geoIndex.get(spans)
+}
+
+// Polygon returns the index entries in the cells covering polygon. Inside entries are in cells entirely within
+// the polygon, boundary entries are in cells crossed by its edges.
+func (geoIndex *geoIndex) Polygon(polygon *Polygon) (inside []interface{}, boundary []interface{}) {
+	inside = make([]interface{}, 0)
+	boundary = make([]interface{}, 0)
+
+	if polygon.isEmpty() {
+		return inside, boundary
 	}
 
+	cells := newPolygonCells(polygon, geoIndex.scheme)
+
+	geoIndex.visit(cells.spans(), func(c cell, entry interface{}) bool {
+		if cells.isBoundary(c) {
+			boundary = append(boundary, entry)
+		} else if cells.isInside(c) {
+			inside = append(inside, entry)
+		}
+		return true
+	})
+
+	return inside, boundary
+}
+
+func (geoIndex *geoIndex) get(spans []cellSpan) []interface{} {
+	entries := make([]interface{}, 0, 0)
+
+	geoIndex.visit(spans, func(_ cell, entry interface{}) bool {
+		entries = append(entries, entry)
+		return true
+	})
+
 	return entries
 }
 
-func (g *geoIndex) getCells(minx int, maxx int, miny int, maxy int) []cell {
-	indices := make([]cell, 0)
+// visit calls visitor with each cell in spans that has an entry and its entry, until visitor returns false.
+func (geoIndex *geoIndex) visit(spans []cellSpan, visitor func(c cell, entry interface{}) bool) {
+	if spansSize(spans) > len(geoIndex.index) {
+		geoIndex.visitOccupied(spans, visitor)
+		return
+	}
 
-	for x := minx; x <= maxx; x++ {
-		for y := miny; y <= maxy; y++ {
-			indices = append(indices, cell{x, y})
+	for _, span := range spans {
+		for y := span.minY; y <= span.maxY; y++ {
+			if indexEntry, ok := geoIndex.index[cell{span.x, y}]; ok {
+				if !visitor(cell{span.x, y}, indexEntry) {
+					return
+				}
+			}
 		}
 	}
+}
+
+// spansSize returns the number of cells in spans.
+func spansSize(spans []cellSpan) int {
+	size := 0
+	for _, span := range spans {
+		size += span.maxY - span.minY + 1
+	}
+	return size
+}
 
-	return indices
+// visitOccupied is like visit, but iterates the occupied cells instead of the cells in spans. It is faster when
+// spans cover far more cells than there are in the index.
+func (geoIndex *geoIndex) visitOccupied(spans []cellSpan, visitor func(c cell, entry interface{}) bool) {
+	rows := make(map[int][]cellSpan, len(spans))
+	for _, span := range spans {
+		rows[span.x] = append(rows[span.x], span)
+	}
+
+	cells := make([]cell, 0)
+	for c := range geoIndex.index {
+		for _, span := range rows[c.x] {
+			if between(float64(c.y), float64(span.minY), float64(span.maxY)) {
+				cells = append(cells, c)
+				break
+			}
+		}
+	}
+
+	// visit the cells in the same order as visit
+	sort.Sort(cellsByPosition(cells))
+
+	for _, c := range cells {
+		if !visitor(c, geoIndex.index[c]) {
+			return
+		}
+	}
+}
+
+type cellsByPosition []cell
+
+func (cells cellsByPosition) Len() int {
+	return len(cells)
+}
+
+func (cells cellsByPosition) Swap(i, j int) {
+	cells[i], cells[j] = cells[j], cells[i]
+}
+
+func (cells cellsByPosition) Less(i, j int) bool {
+	if cells[i].x != cells[j].x {
+		return cells[i].x < cells[j].x
+	}
+	return cells[i].y < cells[j].y
+}
+
+func (geoIndex *geoIndex) addID(id CellID) {
+	geoIndex.pending = append(geoIndex.pending, id)
+}
+
+// sortedIDs returns the sorted ids of the cells in the index, after merging in the pending ids. Queries can run
+// concurrently, so the merge is guarded by idsLock and builds a new slice instead of changing the one they read.
+func (geoIndex *geoIndex) sortedIDs() []CellID {
+	geoIndex.idsLock.Lock()
+	defer geoIndex.idsLock.Unlock()
+
+	if len(geoIndex.pending) == 0 {
+		return geoIndex.ids
+	}
+
+	pending := geoIndex.pending
+	sort.Sort(cellIDs(pending))
+
+	ids := make([]CellID, 0, len(geoIndex.ids)+len(pending))
+	i, j := 0, 0
+	for i < len(geoIndex.ids) && j < len(pending) {
+		if geoIndex.ids[i] < pending[j] {
+			ids = append(ids, geoIndex.ids[i])
+			i++
+		} else {
+			ids = append(ids, pending[j])
+			j++
+		}
+	}
+	ids = append(ids, geoIndex.ids[i:]...)
+	ids = append(ids, pending[j:]...)
+
+	geoIndex.ids = ids
+	geoIndex.pending = nil
+
+	return ids
+}
+
+// visitCover calls visitor with each cell within the cells of cover and its entry, scanning the sorted ids of each
+// cell of cover as a contiguous interval, until visitor returns false.
+func (geoIndex *geoIndex) visitCover(ordered orderedScheme, cover []CellID, visitor func(c cell, entry interface{}) bool) {
+	ids := geoIndex.sortedIDs()
+
+	for _, coverID := range cover {
+		rangeMin, rangeMax := coverID.RangeMin(), coverID.RangeMax()
+		i := sort.Search(len(ids), func(i int) bool { return ids[i] >= rangeMin })
+
+		for ; i < len(ids) && ids[i] <= rangeMax; i++ {
+			c := ordered.cellOfID(ids[i])
+			if !visitor(c, geoIndex.index[c]) {
+				return
+			}
+		}
+	}
 }
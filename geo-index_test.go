@@ -3,6 +3,8 @@ package geoindex
 import (
 	"strconv"
 	"testing"
+
+	"github.com/stretchr/testify/assert"
 )
 
 type TestEntry struct {
@@ -50,3 +52,171 @@ func TestGeoIndexRange(t *testing.T) {
 		t.Error("Invalid number of stations")
 	}
 }
+
+func TestGeoIndexRangeAcrossRows(t *testing.T) {
+	index := newGeoIndex(Km(0.5), newTestEntry)
+
+	oslo := &GeoPoint{"Oslo", 59.9139, 10.7522}
+	quito := &GeoPoint{"Quito", -0.1807, -78.4678}
+
+	for _, center := range []Point{oslo, quito} {
+		for i := -10; i <= 10; i++ {
+			point := &GeoPoint{"", center.Lat() + float64(i)*0.002, center.Lon() + float64(i)*0.002}
+			index.AddEntryAt(point).(*TestEntry).Add(point)
+		}
+	}
+
+	for _, center := range []Point{oslo, quito} {
+		topLeft := &GeoPoint{"", center.Lat() + 0.021, center.Lon() - 0.021}
+		bottomRight := &GeoPoint{"", center.Lat() - 0.021, center.Lon() + 0.021}
+
+		count := 0
+		for _, entry := range index.Range(topLeft, bottomRight) {
+			count += entry.(*TestEntry).count
+		}
+
+		assert.Equal(t, 21, count)
+	}
+}
+
+func TestGeoIndexVisitOccupied(t *testing.T) {
+	index := newGeoIndex(Km(0.5), newTestEntry)
+
+	for _, point := range tubeStations() {
+		index.AddEntryAt(point).(*TestEntry).Add(point)
+	}
+
+	for _, spans := range [][]cellSpan{
+		aroundSpans(index.scheme.(latLonGrid), charring, Km(2)),
+		aroundSpans(index.scheme.(latLonGrid), charring, Km(50)),
+		index.scheme.rangeCells(reykjavik, ankara),
+	} {
+		entries := make([]interface{}, 0)
+		for _, span := range spans {
+			for y := span.minY; y <= span.maxY; y++ {
+				if entry, ok := index.index[cell{span.x, y}]; ok {
+					entries = append(entries, entry)
+				}
+			}
+		}
+
+		occupied := make([]interface{}, 0)
+		index.visitOccupied(spans, func(_ cell, entry interface{}) bool {
+			occupied = append(occupied, entry)
+			return true
+		})
+
+		assert.Equal(t, entries, occupied)
+		assert.Equal(t, entries, index.get(spans))
+	}
+}
+
+func TestGeoIndexVisitBetween(t *testing.T) {
+	index := newGeoIndex(Km(0.5), newTestEntry)
+
+	for _, point := range tubeStations() {
+		index.AddEntryAt(point).(*TestEntry).Add(point)
+	}
+
+	center := index.GetEntryAt(charring)
+	entries := make([]interface{}, 0)
+	index.VisitBetween(charring, Km(2), Km(3), func(entry interface{}) bool {
+		entries = append(entries, entry)
+		return true
+	})
+
+	assert.NotEmpty(t, entries)
+	assert.NotContains(t, entries, center)
+	assert.Subset(t, index.Around(charring, Km(3)), entries)
+	assert.Contains(t, index.Around(charring, Km(3)), center)
+
+	entries = entries[:0]
+	index.VisitBetween(charring, 0, Km(3), func(entry interface{}) bool {
+		entries = append(entries, entry)
+		return true
+	})
+	assert.ElementsMatch(t, index.Around(charring, Km(3)), entries)
+}
+
+func TestGeoIndexVisitRangeInside(t *testing.T) {
+	index := newGeoIndex(Km(0.5), newTestEntry)
+
+	for _, point := range tubeStations() {
+		index.AddEntryAt(point).(*TestEntry).Add(point)
+	}
+
+	entries := make([]interface{}, 0)
+	inside := 0
+	index.VisitRangeInside(oxford, embankment, func(entry interface{}, entryInside bool) bool {
+		entries = append(entries, entry)
+		if entryInside {
+			inside++
+		}
+		return true
+	})
+
+	assert.Equal(t, index.Range(oxford, embankment), entries)
+	assert.True(t, inside > 0 && inside < len(entries))
+	assert.True(t, index.cellInRange(index.scheme.cellOf(leicester), oxford, embankment))
+	assert.False(t, index.cellInRange(index.scheme.cellOf(oxford), oxford, embankment))
+}
+
+func TestGeoIndexCellInRange(t *testing.T) {
+	index := newGeoIndex(Km(1), newTestEntry)
+	fiji := index.scheme.cellOf(&GeoPoint{"", -17.5, 179.99})
+
+	assert.True(t, index.cellInRange(fiji, &GeoPoint{"", -17, 179}, &GeoPoint{"", -18, -179}))
+	assert.False(t, index.cellInRange(fiji, &GeoPoint{"", -17, -179}, &GeoPoint{"", -18, 179}))
+	assert.True(t, index.cellInRange(fiji, &GeoPoint{"", -17, -180}, &GeoPoint{"", -18, 180}))
+
+	// the polar cells span all longitudes
+	pole := index.scheme.cellOf(&GeoPoint{"", 89.999, 0})
+	assert.True(t, index.cellInRange(pole, &GeoPoint{"", 90, -180}, &GeoPoint{"", 89, 180}))
+	assert.False(t, index.cellInRange(pole, &GeoPoint{"", 90, 170}, &GeoPoint{"", 89, -170}))
+}
+
+func TestGeoIndexCellWithin(t *testing.T) {
+	index := newGeoIndex(Km(1), newTestEntry)
+	c := index.scheme.cellOf(charring)
+
+	assert.False(t, index.cellWithin(c, charring, Km(0.5)))
+	assert.True(t, index.cellWithin(c, charring, Km(2)))
+	assert.False(t, index.cellWithin(c, charring, Meters(0)))
+
+	// the polar cells span all longitudes
+	pole := &GeoPoint{"", 89.999, 0}
+	assert.False(t, index.cellWithin(index.scheme.cellOf(pole), pole, Km(2)))
+}
+
+func TestGeoIndexVisitExpanding(t *testing.T) {
+	index := newGeoIndex(Km(0.5), newTestEntry)
+
+	for _, point := range tubeStations() {
+		index.AddEntryAt(point).(*TestEntry).Add(point)
+	}
+
+	// every entry within the distance is visited once
+	entries := make([]interface{}, 0)
+	steps := 0
+	index.VisitExpanding(charring, Km(5), func(entry interface{}) bool {
+		entries = append(entries, entry)
+		return true
+	}, func(searched Meters) Meters {
+		steps++
+		return index.growRadius(searched)
+	})
+
+	assert.ElementsMatch(t, index.Around(charring, Km(5)), entries)
+	assert.True(t, steps > 2)
+
+	// the search stops when the radius doesn't grow
+	entries = entries[:0]
+	index.VisitExpanding(charring, Km(5), func(entry interface{}) bool {
+		entries = append(entries, entry)
+		return true
+	}, func(searched Meters) Meters {
+		return searched
+	})
+
+	assert.Equal(t, []interface{}{index.GetEntryAt(charring)}, entries)
+}
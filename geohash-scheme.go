@@ -0,0 +1,162 @@
+package geoindex
+
+import (
+	"strings"
+)
+
+const geohashAlphabet = "0123456789bcdefghjkmnpqrstuvwxyz"
+
+// A scheme where the cells are the geohashes of fixed precision. The row of a cell holds the latitude bits and
+// the column holds the longitude bits of the geohash.
+type geohashScheme struct {
+	precision int
+	latBits   uint
+	lonBits   uint
+}
+
+// NewGeohashScheme creates a scheme where the cells are the geohashes with precision characters.
+func NewGeohashScheme(precision int) CellScheme {
+	return CellScheme{newGeohashScheme(precision)}
+}
+
+func newGeohashScheme(precision int) *geohashScheme {
+	if precision < 1 || precision > 12 {
+		panic("Geohash precision must be between 1 and 12")
+	}
+
+	bits := uint(precision) * 5
+	return &geohashScheme{precision, bits / 2, bits - bits/2}
+}
+
+// NewGeohashPointsIndex creates new PointsIndex that maintains the points in each geohash with precision
+// characters.
+func NewGeohashPointsIndex(precision int) *PointsIndex {
+	return NewSchemePointsIndex(NewGeohashScheme(precision))
+}
+
+// NewGeohashCountIndex creates an index which counts the points in each geohash with precision characters.
+func NewGeohashCountIndex(precision int) *CountIndex {
+	return NewSchemeCountIndex(NewGeohashScheme(precision))
+}
+
+// GeohashOf returns the geohash of point with precision characters.
+func GeohashOf(point Point, precision int) string {
+	g := newGeohashScheme(precision)
+	return g.geohash(g.cellOf(point))
+}
+
+// geohashBounds returns the top left and bottom right corners of geohash, ok is false if it is not valid.
+func geohashBounds(geohash string) (topLeft Point, bottomRight Point, ok bool) {
+	if len(geohash) < 1 || len(geohash) > 12 {
+		return nil, nil, false
+	}
+
+	g := newGeohashScheme(len(geohash))
+	c, ok := g.parse(geohash)
+	if !ok {
+		return nil, nil, false
+	}
+
+	topLeft, bottomRight = g.bounds(c)
+	return topLeft, bottomRight, true
+}
+
+func (g *geohashScheme) rows() int {
+	return 1 << g.latBits
+}
+
+func (g *geohashScheme) columns() int {
+	return 1 << g.lonBits
+}
+
+func (g *geohashScheme) rowOf(lat float64) int {
+	x := int((normalizeLat(lat) - minLat) / 180 * float64(g.rows()))
+	return min(x, g.rows()-1)
+}
+
+func (g *geohashScheme) columnOf(_ int, lon float64) int {
+	y := int((normalizeLon(lon) - minLon) / 360 * float64(g.columns()))
+	return min(y, g.columns()-1)
+}
+
+func (g *geohashScheme) cellOf(point Point) cell {
+	return cell{g.rowOf(point.Lat()), g.columnOf(0, point.Lon())}
+}
+
+func (g *geohashScheme) neighbours(c cell) []cell {
+	topLeft, bottomRight := g.bounds(c)
+	return touchingCells(g, c, topLeft, bottomRight)
+}
+
+func (g *geohashScheme) rangeCells(topLeft Point, bottomRight Point) []cellSpan {
+	return boxSpans(g, bottomRight.Lat(), topLeft.Lat(), topLeft.Lon(), bottomRight.Lon())
+}
+
+func (g *geohashScheme) aroundCells(point Point, distance Meters) []cellSpan {
+	return aroundSpans(g, point, distance)
+}
+
+func (g *geohashScheme) bounds(c cell) (topLeft Point, bottomRight Point) {
+	height := 180 / float64(g.rows())
+	width := 360 / float64(g.columns())
+
+	bottom := minLat + float64(c.x)*height
+	left := minLon + float64(c.y)*width
+
+	return &GeoPoint{"", bottom + height, left}, &GeoPoint{"", bottom, left + width}
+}
+
+func (g *geohashScheme) size() Meters {
+	height := 180 / float64(g.rows()) * float64(latDegreeLength)
+	width := 360 / float64(g.columns()) * float64(lonLength.get(0))
+
+	if height < width {
+		return Meters(height)
+	}
+	return Meters(width)
+}
+
+// geohash returns the geohash of c. The bits of the geohash alternate between longitude and latitude, starting
+// with longitude.
+func (g *geohashScheme) geohash(c cell) string {
+	result := make([]byte, g.precision)
+	latBit, lonBit := g.latBits, g.lonBits
+
+	for i := range result {
+		char := 0
+		for bit := 0; bit < 5; bit++ {
+			char <<= 1
+			if (i*5+bit)%2 == 0 {
+				lonBit--
+				char |= (c.y >> lonBit) & 1
+			} else {
+				latBit--
+				char |= (c.x >> latBit) & 1
+			}
+		}
+		result[i] = geohashAlphabet[char]
+	}
+
+	return string(result)
+}
+
+// parse returns the cell of geohash, which must have precision characters.
+func (g *geohashScheme) parse(geohash string) (c cell, ok bool) {
+	for i, r := range geohash {
+		char := strings.IndexRune(geohashAlphabet, r)
+		if char < 0 {
+			return cell{}, false
+		}
+
+		for bit := 4; bit >= 0; bit-- {
+			value := (char >> uint(bit)) & 1
+			if (i*5+4-bit)%2 == 0 {
+				c.y = c.y<<1 | value
+			} else {
+				c.x = c.x<<1 | value
+			}
+		}
+	}
+
+	return c, true
+}
@@ -0,0 +1,78 @@
+package geoindex
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGeohashOf(t *testing.T) {
+	assert.Equal(t, "u4pruydqqvj", GeohashOf(&GeoPoint{"", 57.64911, 10.40744}, 11))
+	assert.Equal(t, "gcpuvr", GeohashOf(waterloo, 6))
+	assert.Equal(t, "gcpv", GeohashOf(kingsCross, 4))
+	assert.Equal(t, "zzzz", GeohashOf(&GeoPoint{"", 90.0, 180.0}, 4))
+	assert.Equal(t, "0000", GeohashOf(&GeoPoint{"", -90.0, -180.0}, 4))
+}
+
+func TestGeohashBounds(t *testing.T) {
+	topLeft, bottomRight, ok := geohashBounds("u4pruydqqvj")
+	assert.True(t, ok)
+	assert.InDelta(t, 57.64911, (topLeft.Lat()+bottomRight.Lat())/2, 0.000001)
+	assert.InDelta(t, 10.40744, (topLeft.Lon()+bottomRight.Lon())/2, 0.000001)
+
+	for _, geohash := range []string{"", "u4pa", "0123456789bcd"} {
+		_, _, ok = geohashBounds(geohash)
+		assert.False(t, ok)
+	}
+
+	scheme := newGeohashScheme(5)
+	c := scheme.cellOf(waterloo)
+	parsed, ok := scheme.parse(scheme.geohash(c))
+	assert.True(t, ok)
+	assert.Equal(t, c, parsed)
+	assert.Equal(t, 8, len(scheme.neighbours(c)))
+}
+
+func TestGeohashPointsIndex(t *testing.T) {
+	index := NewGeohashPointsIndex(6)
+
+	stations := tubeStations()
+	for _, point := range stations {
+		index.Add(point)
+	}
+
+	expected := []Point{picadilly, charring, coventGarden, embankment, leicester, oxford}
+	assert.True(t, pointsEqualIgnoreOrder(expected, index.Range(oxford, embankment)))
+
+	assert.Equal(t, []Point{charring, embankment, leicester}, index.KNearest(charring, 3, Km(1), all))
+	assert.True(t, pointsEqualIgnoreOrder(bruteForceWithin(stations, charring, Km(2)), index.PointsWithin(charring, Km(2), all)))
+
+	for _, geohash := range []string{"gcpv", "gcpvj", "GCPUV", "gcpuvx"} {
+		within := make([]Point, 0)
+		for _, station := range stations {
+			if GeohashOf(station, len(geohash)) == strings.ToLower(geohash) {
+				within = append(within, station)
+			}
+		}
+
+		assert.True(t, len(within) > 0)
+		assert.True(t, pointsEqualIgnoreOrder(within, index.WithinGeohash(geohash)))
+	}
+
+	assert.Equal(t, 0, len(index.WithinGeohash("gcpva")))
+}
+
+func TestGeohashCountIndex(t *testing.T) {
+	countIndex := NewGeohashCountIndex(4)
+
+	for _, station := range tubeStations() {
+		countIndex.Add(station)
+	}
+
+	count := 0
+	for _, counter := range toCountPoints(countIndex.Range(&GeoPoint{"", 52.0, -1.0}, &GeoPoint{"", 51.0, 1.0})) {
+		count += counter.Count.(int)
+	}
+	assert.Equal(t, len(tubeStations()), count)
+}
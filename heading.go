@@ -0,0 +1,58 @@
+package geoindex
+
+// Heading is implemented by points that move, so that KNearestScored can rank them by whether they are heading
+// towards the query point.
+type Heading interface {
+	// Heading returns the direction of movement in degrees clockwise from north, like BearingTo.
+	Heading() float64
+
+	// Speed returns the speed in meters per second, 0 for a point that is not moving.
+	Speed() float64
+}
+
+// ScoreFunc scores a candidate of KNearestScored given its distance from the query point, the angle in degrees
+// between its heading and the bearing from it to the query point, from 0 when heading straight towards the query
+// point to 180 when heading away, and its speed. Candidates that do not implement Heading or do not move have angle
+// and speed 0. The score is an effective distance, lower is better.
+type ScoreFunc func(distance Meters, angle float64, speed float64) Meters
+
+// HeadingScore returns a ScoreFunc that adds to the distance up to penalty, in proportion to the angle, so a moving
+// point heading away from the query point is penalty farther than one heading towards it.
+func HeadingScore(penalty Meters) ScoreFunc {
+	return func(distance Meters, angle float64, speed float64) Meters {
+		if speed <= 0 {
+			return distance
+		}
+		return distance + penalty*Meters(angle/180)
+	}
+}
+
+// KNearestScored returns the k points within maxDistance of point that match the accept criteria with the lowest
+// score, sorted by score.
+func (points *PointsIndex) KNearestScored(point Point, k int, maxDistance Meters, score ScoreFunc, accept func(p Point) bool) []Point {
+	if k <= 0 {
+		return make([]Point, 0)
+	}
+
+	best := make(scoredHeap, 0, k)
+
+	candidate := func(candidate Point) bool {
+		dist := Distance(point, candidate)
+		if dist > maxDistance {
+			return true
+		}
+
+		angle, speed := 0.0, 0.0
+		if heading, ok := candidate.(Heading); ok && heading.Speed() > 0 {
+			angle = bearingDelta(heading.Heading(), BearingTo(candidate, point))
+			speed = heading.Speed()
+		}
+
+		best.offer(scoredPoint{candidate, float64(score(dist, angle, speed))}, k)
+		return true
+	}
+
+	points.index.VisitAround(point, maxDistance, visitPoints(accept, candidate))
+
+	return best.points()
+}
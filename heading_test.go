@@ -0,0 +1,64 @@
+package geoindex
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type movingDriver struct {
+	*GeoPoint
+	heading float64
+	speed   float64
+}
+
+func (d *movingDriver) Heading() float64 {
+	return d.heading
+}
+
+func (d *movingDriver) Speed() float64 {
+	return d.speed
+}
+
+func TestHeadingScore(t *testing.T) {
+	score := HeadingScore(Meters(500))
+
+	assert.Equal(t, Meters(200), score(Meters(200), 0, 10))
+	assert.Equal(t, Meters(700), score(Meters(200), 180, 10))
+	assert.Equal(t, Meters(450), score(Meters(200), 90, 10))
+	assert.Equal(t, Meters(200), score(Meters(200), 180, 0))
+}
+
+func TestKNearestScored(t *testing.T) {
+	pickup := charring
+
+	// 200 m north of the pickup heading north, away from it
+	away := &movingDriver{NewGeoPoint("away", destination(pickup, 0, Meters(200)).Lat(), pickup.Lon()), 0, 10}
+	// 500 m east of the pickup heading west, towards it
+	towards := &movingDriver{NewGeoPoint("towards", pickup.Lat(), destination(pickup, 90, Meters(500)).Lon()), 270, 10}
+	// 400 m south of the pickup, not moving
+	parked := &movingDriver{NewGeoPoint("parked", destination(pickup, 180, Meters(400)).Lat(), pickup.Lon()), 90, 0}
+	// 300 m west of the pickup, without a heading
+	walking := NewGeoPoint("walking", pickup.Lat(), destination(pickup, 270, Meters(300)).Lon())
+
+	index := NewPointsIndex(Km(0.5))
+	for _, p := range []Point{away, towards, parked, walking} {
+		index.Add(p)
+	}
+
+	distanceOnly := func(distance Meters, angle float64, speed float64) Meters {
+		return distance
+	}
+	assert.Equal(t, index.KNearest(pickup, 4, Km(1), all), index.KNearestScored(pickup, 4, Km(1), distanceOnly, all))
+
+	assert.Equal(t, []Point{walking, parked, towards, away}, index.KNearestScored(pickup, 4, Km(1), HeadingScore(Meters(500)), all))
+	assert.Equal(t, []Point{walking, parked}, index.KNearestScored(pickup, 2, Km(1), HeadingScore(Meters(500)), all))
+
+	notWalking := func(p Point) bool {
+		return p.Id() != "walking"
+	}
+	assert.Equal(t, []Point{parked, towards}, index.KNearestScored(pickup, 2, Km(1), HeadingScore(Meters(500)), notWalking))
+
+	assert.Equal(t, []Point{walking, away}, index.KNearestScored(pickup, 4, Meters(350), HeadingScore(Meters(500)), all))
+	assert.Empty(t, index.KNearestScored(pickup, 0, Km(1), HeadingScore(Meters(500)), all))
+}
@@ -0,0 +1,193 @@
+package geoindex
+
+import (
+	"math"
+)
+
+var (
+	sqrt3 = math.Sqrt(3)
+
+	// Neighbour offsets in axial coordinates, as q and r.
+	hexDirections = [6][2]int{{1, 0}, {1, -1}, {0, -1}, {-1, 0}, {-1, 1}, {0, 1}}
+)
+
+// A scheme that splits the earth surface in pointy top hexagons. The hexagons are laid out on the Mercator
+// projection, so they are regular everywhere, but their size is exact only at the latitude of the scheme and grows
+// towards the equator and shrinks towards the poles. Points closer to the poles than polarLat fall in the hexagons
+// at polarLat. Hexagons are cut at the antimeridian. The row of a cell is the r and the column is the q axial
+// coordinate of the hexagon.
+type hexScheme struct {
+	edge  Meters
+	scale float64
+}
+
+// NewHexScheme creates a scheme with hexagons with edge length edge at latitude lat.
+func NewHexScheme(edge Meters, lat float64) CellScheme {
+	return CellScheme{newHexScheme(edge, lat)}
+}
+
+func newHexScheme(edge Meters, lat float64) *hexScheme {
+	return &hexScheme{edge, float64(earthRadius) * math.Cos(toRadians(lat))}
+}
+
+// NewHexPointsIndex creates new PointsIndex that maintains the points in each hexagon of NewHexScheme(edge, lat).
+func NewHexPointsIndex(edge Meters, lat float64) *PointsIndex {
+	return NewSchemePointsIndex(NewHexScheme(edge, lat))
+}
+
+// NewHexCountIndex creates an index which counts the points in each hexagon of NewHexScheme(edge, lat).
+func NewHexCountIndex(edge Meters, lat float64) *CountIndex {
+	return NewSchemeCountIndex(NewHexScheme(edge, lat))
+}
+
+// boundary returns the six corners of the hexagon c, clockwise starting from the top.
+func (h *hexScheme) boundary(c cell) []Point {
+	x, y := h.centerXY(c)
+
+	corners := make([]Point, 6)
+	for i := range corners {
+		angle := toRadians(90 - 60*float64(i))
+		corners[i] = h.unproject(x+float64(h.edge)*math.Cos(angle), y+float64(h.edge)*math.Sin(angle))
+	}
+
+	return corners
+}
+
+// project returns the Mercator coordinates of lat, lon in meters at the latitude of the scheme.
+func (h *hexScheme) project(lat, lon float64) (x, y float64) {
+	lat = math.Max(-polarLat, math.Min(polarLat, lat))
+
+	x = h.scale * toRadians(normalizeLon(lon))
+	y = h.scale * math.Log(math.Tan(math.Pi/4+toRadians(lat)/2))
+
+	return x, y
+}
+
+func (h *hexScheme) unproject(x, y float64) Point {
+	lat := toDegrees(2*math.Atan(math.Exp(y/h.scale)) - math.Pi/2)
+	lon := toDegrees(x / h.scale)
+
+	return &GeoPoint{"", lat, lon}
+}
+
+func (h *hexScheme) centerXY(c cell) (x, y float64) {
+	edge := float64(h.edge)
+	return edge * sqrt3 * (float64(c.y) + float64(c.x)/2), edge * 1.5 * float64(c.x)
+}
+
+func (h *hexScheme) center(c cell) Point {
+	return h.unproject(h.centerXY(c))
+}
+
+func (h *hexScheme) cellOf(point Point) cell {
+	x, y := h.project(point.Lat(), point.Lon())
+	edge := float64(h.edge)
+
+	q := (sqrt3/3*x - y/3) / edge
+	r := 2.0 / 3.0 * y / edge
+
+	return hexRound(q, r)
+}
+
+// hexRound returns the hexagon that contains the fractional axial coordinates q and r.
+func hexRound(q, r float64) cell {
+	s := -q - r
+
+	rq := math.Round(q)
+	rr := math.Round(r)
+	rs := math.Round(s)
+
+	dq := math.Abs(rq - q)
+	dr := math.Abs(rr - r)
+	ds := math.Abs(rs - s)
+
+	if dq > dr && dq > ds {
+		rq = -rr - rs
+	} else if dr > ds {
+		rr = -rq - rs
+	}
+
+	return cell{int(rr), int(rq)}
+}
+
+func (h *hexScheme) neighbours(c cell) []cell {
+	result := make([]cell, 0, len(hexDirections))
+	for _, direction := range hexDirections {
+		result = append(result, cell{c.x + direction[1], c.y + direction[0]})
+	}
+
+	return result
+}
+
+func (h *hexScheme) rangeCells(topLeft Point, bottomRight Point) []cellSpan {
+	return h.boxSpans(bottomRight.Lat(), topLeft.Lat(), topLeft.Lon(), bottomRight.Lon())
+}
+
+func (h *hexScheme) aroundCells(point Point, distance Meters) []cellSpan {
+	return h.boxSpans(aroundBox(point, distance))
+}
+
+// boxSpans returns the spans of the hexagons whose bounding box intersects the rectangle between lat and lon
+// ranges.
+func (h *hexScheme) boxSpans(minLat, maxLat, minLon, maxLon float64) []cellSpan {
+	edge := float64(h.edge)
+	width := sqrt3 * edge
+
+	_, minY := h.project(minLat, 0)
+	_, maxY := h.project(maxLat, 0)
+	minr := int(math.Ceil((minY - edge) / (1.5 * edge)))
+	maxr := int(math.Floor((maxY + edge) / (1.5 * edge)))
+
+	intervals := lonIntervals(minLon, maxLon)
+
+	spans := make([]cellSpan, 0, (maxr-minr+1)*len(intervals))
+	for r := minr; r <= maxr; r++ {
+		rowStart := len(spans)
+
+		for _, interval := range intervals {
+			minX, _ := h.project(0, interval[0])
+			maxX, _ := h.project(0, interval[1])
+
+			minq := int(math.Ceil((minX-width/2)/width - float64(r)/2))
+			maxq := int(math.Floor((maxX+width/2)/width - float64(r)/2))
+			span := cellSpan{r, minq, maxq}
+
+			// the two sides of a wrapped range can share hexagons when the hexagons are wide
+			if len(spans) > rowStart && spans[rowStart].minY <= span.maxY && span.minY <= spans[rowStart].maxY {
+				if span.minY < spans[rowStart].minY {
+					spans[rowStart].minY = span.minY
+				}
+				if span.maxY > spans[rowStart].maxY {
+					spans[rowStart].maxY = span.maxY
+				}
+				continue
+			}
+
+			spans = append(spans, span)
+		}
+	}
+
+	return spans
+}
+
+// bounds returns the bounding box of the hexagon c. The hexagons that reach polarLat also contain the points closer
+// to the pole, so their box extends to it.
+func (h *hexScheme) bounds(c cell) (topLeft Point, bottomRight Point) {
+	x, y := h.centerXY(c)
+	edge := float64(h.edge)
+	halfWidth := sqrt3 / 2 * edge
+
+	topLeft, bottomRight = h.unproject(x-halfWidth, y+edge), h.unproject(x+halfWidth, y-edge)
+	if topLeft.Lat() >= polarLat {
+		topLeft = &GeoPoint{"", 90, topLeft.Lon()}
+	}
+	if bottomRight.Lat() <= -polarLat {
+		bottomRight = &GeoPoint{"", -90, bottomRight.Lon()}
+	}
+
+	return topLeft, bottomRight
+}
+
+func (h *hexScheme) size() Meters {
+	return Meters(sqrt3) * h.edge
+}
@@ -0,0 +1,112 @@
+package geoindex
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHexSchemeGeometry(t *testing.T) {
+	scheme := newHexScheme(Km(0.5), 51.5)
+
+	for _, point := range tubeStations() {
+		c := scheme.cellOf(point)
+		center := scheme.center(c)
+		assert.Equal(t, c, scheme.cellOf(center))
+
+		// the point is closer to the center of its hexagon than to the centers of the neighbours
+		for _, neighbour := range scheme.neighbours(c) {
+			neighbourCenter := scheme.center(neighbour)
+			assert.InDelta(t, float64(scheme.size()), float64(distance(center, neighbourCenter)), 10.0)
+			assert.True(t, distance(point, center) <= distance(point, neighbourCenter))
+		}
+
+		corners := scheme.boundary(c)
+		assert.Equal(t, 6, len(corners))
+		for _, corner := range corners {
+			assert.InDelta(t, 500.0, float64(distance(center, corner)), 5.0)
+		}
+
+		topLeft, bottomRight := scheme.bounds(c)
+		assert.True(t, between(point.Lat(), bottomRight.Lat(), topLeft.Lat()))
+		assert.True(t, between(point.Lon(), topLeft.Lon(), bottomRight.Lon()))
+	}
+
+	scheme = newHexScheme(Km(5), 51.5)
+
+	// the points closer to the poles than polarLat fall in the hexagons at polarLat
+	for _, point := range []Point{
+		&GeoPoint{"", 84.99, 10},
+		&GeoPoint{"", 87.5, -45.3},
+		&GeoPoint{"", 90, 0},
+		&GeoPoint{"", -84.69, 102.95},
+		&GeoPoint{"", -89.99, 179.9},
+	} {
+		topLeft, bottomRight := scheme.bounds(scheme.cellOf(point))
+		assert.True(t, between(point.Lat(), bottomRight.Lat(), topLeft.Lat()))
+		assert.True(t, between(point.Lon(), topLeft.Lon(), bottomRight.Lon()))
+	}
+
+	topLeft, bottomRight := scheme.bounds(scheme.cellOf(&GeoPoint{"", 60, 0}))
+	assert.True(t, topLeft.Lat() < polarLat && bottomRight.Lat() > -polarLat)
+}
+
+func TestHexPointsIndex(t *testing.T) {
+	index := NewHexPointsIndex(Km(0.3), 51.5)
+
+	stations := tubeStations()
+	for _, point := range stations {
+		index.Add(point)
+	}
+
+	expected := []Point{picadilly, charring, coventGarden, embankment, leicester, oxford}
+	assert.True(t, pointsEqualIgnoreOrder(expected, index.Range(oxford, embankment)))
+
+	assert.Equal(t, []Point{charring, embankment, leicester}, index.KNearest(charring, 3, Km(1), all))
+	assert.True(t, pointsEqualIgnoreOrder(bruteForceWithin(stations, charring, Km(2)), index.PointsWithin(charring, Km(2), all)))
+}
+
+func TestHexCountIndex(t *testing.T) {
+	scheme := NewHexScheme(Km(1), 51.5)
+	countIndex := NewSchemeCountIndex(scheme)
+
+	stations := tubeStations()
+	for _, station := range stations {
+		countIndex.Add(station)
+	}
+
+	hexagons := toCountPoints(countIndex.Range(&GeoPoint{"", 52.0, -1.0}, &GeoPoint{"", 51.0, 1.0}))
+
+	count := 0
+	for _, hexagon := range hexagons {
+		count += hexagon.Count.(int)
+	}
+	assert.Equal(t, len(stations), count)
+	assert.True(t, len(hexagons) > 100)
+}
+
+func TestHexWideAntimeridianRange(t *testing.T) {
+	hex := NewHexPointsIndex(Km(2000), 0)
+	grid := NewPointsIndex(Km(100))
+	for lat := -80.0; lat <= 80; lat += 5 {
+		for lon := -180.0; lon < 180; lon += 5 {
+			point := &GeoPoint{fmt.Sprintf("%v,%v", lat, lon), lat, lon}
+			hex.Add(point)
+			grid.Add(point)
+		}
+	}
+
+	// the two sides of the ranges share hexagons
+	for _, box := range [][2]Point{
+		{&GeoPoint{"", 11, -175}, &GeoPoint{"", 9, -176}},
+		{&GeoPoint{"", 60, 170}, &GeoPoint{"", -60, 160}},
+	} {
+		expected := grid.Range(box[0], box[1])
+
+		points := hex.Range(box[0], box[1])
+		assert.Equal(t, len(expected), len(points))
+		assert.True(t, pointsEqualIgnoreOrder(expected, points))
+		assert.Equal(t, len(expected), hex.CountRange(box[0], box[1]))
+	}
+}
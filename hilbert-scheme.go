@@ -0,0 +1,173 @@
+package geoindex
+
+import (
+	"sort"
+)
+
+// A scheme where the cells are the CellIDs of a fixed level. The row of a cell is the row of latitude and the
+// column is the column of longitude of the CellID.
+type hilbertScheme struct {
+	level int
+}
+
+// NewHilbertScheme creates a scheme where the cells are the CellIDs at level. Indexes using this scheme keep the
+// ids of their cells sorted, so a Range query scans a few contiguous intervals of ids.
+func NewHilbertScheme(level int) CellScheme {
+	return CellScheme{newHilbertScheme(level)}
+}
+
+func newHilbertScheme(level int) *hilbertScheme {
+	if level < 0 || level > MaxCellLevel {
+		panic("Cell level must be between 0 and 30")
+	}
+
+	return &hilbertScheme{level}
+}
+
+// NewHilbertPointsIndex creates new PointsIndex that maintains the points in each cell of NewHilbertScheme(level).
+func NewHilbertPointsIndex(level int) *PointsIndex {
+	return NewSchemePointsIndex(NewHilbertScheme(level))
+}
+
+// A cellScheme with cells numbered along a curve, so that the cells covering an area are a few intervals of ids.
+type orderedScheme interface {
+	cellScheme
+
+	// cellID returns the id of c.
+	cellID(c cell) CellID
+
+	// cellOfID returns the cell of id, which is at the level of the scheme.
+	cellOfID(id CellID) cell
+
+	// rangeCover returns a few cells, at the level of the scheme or above, covering the rectangle between topLeft
+	// and bottomRight. The cells can extend beyond the rectangle.
+	rangeCover(topLeft Point, bottomRight Point) []CellID
+}
+
+func (h *hilbertScheme) cells() int {
+	return 1 << uint(h.level)
+}
+
+func (h *hilbertScheme) rowOf(lat float64) int {
+	x := int((normalizeLat(lat) - minLat) / 180 * float64(h.cells()))
+	return min(x, h.cells()-1)
+}
+
+func (h *hilbertScheme) columnOf(_ int, lon float64) int {
+	y := int((normalizeLon(lon) - minLon) / 360 * float64(h.cells()))
+	return min(y, h.cells()-1)
+}
+
+func (h *hilbertScheme) cellOf(point Point) cell {
+	return cell{h.rowOf(point.Lat()), h.columnOf(0, point.Lon())}
+}
+
+func (h *hilbertScheme) cellID(c cell) CellID {
+	return cellIDFromCoords(uint64(c.y), uint64(c.x), h.level)
+}
+
+func (h *hilbertScheme) cellOfID(id CellID) cell {
+	x, y := id.coords()
+	return cell{int(y), int(x)}
+}
+
+func (h *hilbertScheme) neighbours(c cell) []cell {
+	topLeft, bottomRight := h.bounds(c)
+	return touchingCells(h, c, topLeft, bottomRight)
+}
+
+func (h *hilbertScheme) rangeCells(topLeft Point, bottomRight Point) []cellSpan {
+	return boxSpans(h, bottomRight.Lat(), topLeft.Lat(), topLeft.Lon(), bottomRight.Lon())
+}
+
+func (h *hilbertScheme) aroundCells(point Point, distance Meters) []cellSpan {
+	return aroundSpans(h, point, distance)
+}
+
+func (h *hilbertScheme) bounds(c cell) (topLeft Point, bottomRight Point) {
+	return h.cellID(c).Bounds()
+}
+
+func (h *hilbertScheme) size() Meters {
+	height := 180 / float64(h.cells()) * float64(latDegreeLength)
+	width := 360 / float64(h.cells()) * float64(lonLength.get(0))
+
+	if height < width {
+		return Meters(height)
+	}
+	return Meters(width)
+}
+
+// maxCoverCells is the most cells rangeCover returns for each side of the antimeridian. A cover with more cells
+// fits the rectangle better, but each cell is another interval of ids to scan.
+const maxCoverCells = 64
+
+func (h *hilbertScheme) rangeCover(topLeft Point, bottomRight Point) []CellID {
+	cover := make([]CellID, 0)
+
+	for _, interval := range lonIntervals(topLeft.Lon(), bottomRight.Lon()) {
+		cover = h.appendCover(cover, bottomRight.Lat(), topLeft.Lat(), interval[0], interval[1])
+	}
+
+	return disjointCover(cover)
+}
+
+// disjointCover sorts cover and removes the cells within other cells of cover, so that no id is scanned twice. The
+// covers of the two sides of the antimeridian can overlap when the gap between them is narrower than their cells.
+func disjointCover(cover []CellID) []CellID {
+	sort.Sort(cellIDs(cover))
+
+	result := make([]CellID, 0, len(cover))
+	for _, id := range cover {
+		if len(result) > 0 && result[len(result)-1].Contains(id) {
+			continue
+		}
+
+		for len(result) > 0 && id.Contains(result[len(result)-1]) {
+			result = result[:len(result)-1]
+		}
+
+		result = append(result, id)
+	}
+
+	return result
+}
+
+// appendCover appends to cover at most maxCoverCells cells covering the rectangle between lat and lon ranges. The
+// cells crossing the edges of the rectangle are split a level at a time, until the index level or until splitting
+// them would exceed maxCoverCells. Cells that are entirely within the rectangle are not split further. The cover
+// can extend beyond the rectangle, so the points in it must still be filtered.
+func (h *hilbertScheme) appendCover(cover []CellID, minLat, maxLat, minLon, maxLon float64) []CellID {
+	within := make([]CellID, 0)
+	edges := []CellID{CellID(1) << (2 * MaxCellLevel)}
+
+	for len(edges) > 0 && edges[0].Level() < h.level {
+		nextWithin := within
+		nextEdges := make([]CellID, 0, len(edges)*4)
+
+		for _, id := range edges {
+			for _, child := range id.Children() {
+				topLeft, bottomRight := child.Bounds()
+
+				if topLeft.Lat() < minLat || bottomRight.Lat() > maxLat || bottomRight.Lon() < minLon || topLeft.Lon() > maxLon {
+					continue
+				}
+
+				if bottomRight.Lat() >= minLat && topLeft.Lat() <= maxLat && topLeft.Lon() >= minLon && bottomRight.Lon() <= maxLon {
+					nextWithin = append(nextWithin, child)
+				} else {
+					nextEdges = append(nextEdges, child)
+				}
+			}
+		}
+
+		if len(nextWithin)+len(nextEdges) > maxCoverCells {
+			break
+		}
+
+		within, edges = nextWithin, nextEdges
+	}
+
+	cover = append(cover, within...)
+	return append(cover, edges...)
+}
@@ -0,0 +1,149 @@
+package geoindex
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHilbertPointsIndex(t *testing.T) {
+	index := NewHilbertPointsIndex(16)
+
+	stations := tubeStations()
+	for _, point := range stations {
+		index.Add(point)
+	}
+
+	ids := index.index.sortedIDs()
+	assert.Equal(t, len(index.index.index), len(ids))
+	for i := 1; i < len(ids); i++ {
+		assert.True(t, ids[i-1] < ids[i])
+	}
+
+	expected := []Point{picadilly, charring, coventGarden, embankment, leicester, oxford}
+	assert.True(t, pointsEqualIgnoreOrder(expected, index.Range(oxford, embankment)))
+
+	assert.Equal(t, []Point{charring, embankment, leicester}, index.KNearest(charring, 3, Km(1), all))
+	assert.True(t, pointsEqualIgnoreOrder(bruteForceWithin(stations, charring, Km(2)), index.PointsWithin(charring, Km(2), all)))
+}
+
+func TestInCell(t *testing.T) {
+	stations := tubeStations()
+
+	for _, index := range []*PointsIndex{NewHilbertPointsIndex(14), NewPointsIndex(Km(0.5))} {
+		for _, point := range stations {
+			index.Add(point)
+		}
+
+		for _, level := range []int{8, 12, 14, 18} {
+			id := CellIDOf(charring, level)
+
+			expected := make([]Point, 0)
+			for _, station := range stations {
+				if CellIDOf(station, level) == id {
+					expected = append(expected, station)
+				}
+			}
+
+			assert.True(t, len(expected) > 0)
+			assert.True(t, pointsEqualIgnoreOrder(expected, index.InCell(id)))
+		}
+	}
+}
+
+func TestHilbertAntimeridianRange(t *testing.T) {
+	index := NewHilbertPointsIndex(16)
+	for _, point := range antimeridianPoints {
+		index.Add(point)
+	}
+
+	topLeft := &GeoPoint{"", -17.0, 178.5}
+	bottomRight := &GeoPoint{"", -18.5, -178.5}
+	assert.True(t, pointsEqualIgnoreOrder([]Point{levuka, lakeba, vanuaBal}, index.Range(topLeft, bottomRight)))
+}
+
+func bruteForceRange(points []Point, topLeft Point, bottomRight Point) []Point {
+	result := make([]Point, 0)
+	for _, p := range points {
+		if inRange(p, topLeft, bottomRight) {
+			result = append(result, p)
+		}
+	}
+	return result
+}
+
+func TestHilbertAddAfterRange(t *testing.T) {
+	index := NewHilbertPointsIndex(20)
+	stations := tubeStations()
+
+	// the ids of the cells added between queries are sorted in by the next query
+	for i, point := range stations {
+		index.Add(point)
+		if i%50 == 0 {
+			assert.True(t, pointsEqualIgnoreOrder(bruteForceRange(stations[:i+1], oxford, embankment), index.Range(oxford, embankment)))
+		}
+	}
+
+	ids := index.index.sortedIDs()
+	assert.Equal(t, len(index.index.index), len(ids))
+	for i := 1; i < len(ids); i++ {
+		assert.True(t, ids[i-1] < ids[i])
+	}
+	assert.True(t, pointsEqualIgnoreOrder(bruteForceRange(stations, oxford, embankment), index.Range(oxford, embankment)))
+}
+
+func BenchmarkHilbertPointIndexAdd(b *testing.B) {
+	bench(b).AddWorldWide(NewHilbertPointsIndex(20))
+}
+
+func TestHilbertRangeCover(t *testing.T) {
+	scheme := newHilbertScheme(20)
+
+	for _, box := range [][2]Point{{oxford, embankment}, {reykjavik, ankara}, {&GeoPoint{"", -17.0, 178.5}, &GeoPoint{"", -18.5, -178.5}}} {
+		cover := scheme.rangeCover(box[0], box[1])
+		assert.NotEmpty(t, cover)
+		assert.True(t, len(cover) <= 2*maxCoverCells)
+	}
+
+	index := NewSchemeCountIndex(NewHilbertScheme(20))
+	points := make([]Point, 0)
+	for i := 0; i < 5000; i++ {
+		point := randomPointWorldWide()
+		points = append(points, point)
+		index.Add(point)
+	}
+
+	count := 0
+	for _, counter := range index.Range(reykjavik, ankara) {
+		count += counter.(*CountPoint).Count.(int)
+	}
+	assert.Equal(t, len(bruteForceRange(points, reykjavik, ankara)), count)
+}
+
+func BenchmarkHilbertPointIndexEuropeRange(b *testing.B) {
+	bench(b).EuropeRange(NewHilbertPointsIndex(20))
+}
+
+func TestHilbertWideAntimeridianRange(t *testing.T) {
+	hilbert := NewHilbertPointsIndex(12)
+	grid := NewPointsIndex(Km(100))
+	for lat := -87.0; lat <= 87; lat += 3 {
+		for lon := -180.0; lon < 180; lon += 3 {
+			point := &GeoPoint{fmt.Sprintf("%v,%v", lat, lon), lat, lon}
+			hilbert.Add(point)
+			grid.Add(point)
+		}
+	}
+
+	// the ranges wrap around the antimeridian and leave out a gap narrower than the cells of the covers
+	for _, lons := range [][2]float64{{-150, -170}, {170, 160}, {-140, -170}, {179, 178}} {
+		topLeft, bottomRight := &GeoPoint{"", 60, lons[0]}, &GeoPoint{"", -60, lons[1]}
+		expected := grid.Range(topLeft, bottomRight)
+
+		points := hilbert.Range(topLeft, bottomRight)
+		assert.Equal(t, len(expected), len(points))
+		assert.True(t, pointsEqualIgnoreOrder(expected, points))
+		assert.Equal(t, len(expected), hilbert.CountRange(topLeft, bottomRight))
+	}
+}
@@ -0,0 +1,120 @@
+package geoindex
+
+import (
+	"sort"
+)
+
+// Join calls fn with each pair of a point p of a and a point q of b within maxDistance of each other and their
+// distance. It visits each occupied cell of a once, with the points of b in the cells around it, so the indexes can
+// have different resolutions or schemes. When a and b are the same index each point is also paired with itself.
+func Join(a, b *PointsIndex, maxDistance Meters, fn func(p, q Point, d Meters)) {
+	joinCells(a, b, maxDistance, func(left []Point, right []Point) {
+		for _, p := range left {
+			for _, q := range right {
+				if d := Distance(p, q); d <= maxDistance {
+					fn(p, q, d)
+				}
+			}
+		}
+	})
+}
+
+// KNearestJoin is like Join, but calls fn with the k nearest points of b within maxDistance of each point of a only,
+// nearest first.
+func KNearestJoin(a, b *PointsIndex, k int, maxDistance Meters, fn func(p, q Point, d Meters)) {
+	if k <= 0 {
+		return
+	}
+
+	nearest := make(nearestHeap, 0, k)
+
+	joinCells(a, b, maxDistance, func(left []Point, right []Point) {
+		for _, p := range left {
+			nearest = nearest[:0]
+			for _, q := range right {
+				if d := Distance(p, q); d <= maxDistance {
+					nearest.offer(PointDistance{Point: q, Distance: d}, k)
+				}
+			}
+
+			sort.Sort(byDistance(nearest))
+			for _, q := range nearest {
+				fn(p, q.Point, q.Distance)
+			}
+		}
+	})
+}
+
+// joinCells calls join with the points of each occupied cell of a and the points of b that can be within
+// maxDistance of them.
+func joinCells(a, b *PointsIndex, maxDistance Meters, join func(left []Point, right []Point)) {
+	left := make([]Point, 0)
+	right := make([]Point, 0)
+
+	appendLeft := func(value interface{}) bool {
+		left = append(left, value.(Point))
+		return true
+	}
+
+	appendRight := func(point Point) bool {
+		right = append(right, point)
+		return true
+	}
+
+	for c, entry := range a.index.index {
+		left = left[:0]
+		entry.(set).Visit(appendLeft)
+		if len(left) == 0 {
+			continue
+		}
+
+		topLeft, bottomRight := a.index.scheme.bounds(c)
+		minLat, maxLat, minLon, maxLon := aroundRectBox(topLeft, bottomRight, maxDistance)
+
+		right = right[:0]
+		b.RangeFunc(&GeoPoint{"", maxLat, minLon}, &GeoPoint{"", minLat, maxLon}, appendRight)
+
+		join(left, right)
+	}
+}
+
+// ReverseKNearest returns the points of requests that have driver among their k nearest points of drivers within
+// maxDistance, nearest to driver first. A request qualifies when fewer than k other drivers are closer to it than
+// driver, so ties are in favour of driver. Only the requests within maxDistance of driver are candidates, and for
+// each of them the drivers are counted within the distance to driver only, until k are found.
+func ReverseKNearest(drivers, requests *PointsIndex, driver Point, k int, maxDistance Meters) []Point {
+	reverse := make([]PointDistance, 0)
+	if k <= 0 {
+		return make([]Point, 0)
+	}
+
+	candidate := func(request Point) bool {
+		return Distance(driver, request) <= maxDistance
+	}
+
+	requests.index.VisitAround(driver, maxDistance, visitPoints(candidate, func(request Point) bool {
+		d := Distance(request, driver)
+
+		closer := 0
+		drivers.PointsWithinFunc(request, d, func(other Point) bool {
+			if other.Id() != driver.Id() {
+				closer++
+			}
+			return closer < k
+		})
+
+		if closer < k {
+			reverse = append(reverse, PointDistance{Point: request, Distance: d})
+		}
+		return true
+	}))
+
+	sort.Sort(byDistance(reverse))
+
+	result := make([]Point, len(reverse))
+	for i, request := range reverse {
+		result[i] = request.Point
+	}
+
+	return result
+}
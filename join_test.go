@@ -0,0 +1,180 @@
+package geoindex
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type joinPair struct {
+	p string
+	q string
+}
+
+func bruteForceJoin(left []Point, right []Point, maxDistance Meters) map[joinPair]Meters {
+	result := make(map[joinPair]Meters)
+	for _, p := range left {
+		for _, q := range right {
+			if d := Distance(p, q); d <= maxDistance {
+				result[joinPair{p.Id(), q.Id()}] = d
+			}
+		}
+	}
+	return result
+}
+
+func randomPoints(n int, generatePoint func() Point) []Point {
+	points := make([]Point, n)
+	for i := range points {
+		points[i] = generatePoint()
+	}
+	return points
+}
+
+func TestJoin(t *testing.T) {
+	rand.Seed(11)
+
+	for _, generatePoint := range []func() Point{randomPoint, randomPointWorldWide} {
+		requests := randomPoints(300, generatePoint)
+		drivers := randomPoints(600, generatePoint)
+
+		for _, indexes := range [][2]*PointsIndex{
+			{NewPointsIndex(Km(0.5)), NewPointsIndex(Km(0.5))},
+			{NewPointsIndex(Km(0.5)), NewPointsIndex(Km(3))},
+			{NewPointsIndex(Km(3)), NewPointsIndex(Meters(200))},
+			{NewHexPointsIndex(Km(1), 51.5), NewQuadtreePointsIndex(8)},
+			{NewHilbertPointsIndex(14), NewPointsIndex(Km(1))},
+		} {
+			a, b := indexes[0], indexes[1]
+			for _, p := range requests {
+				a.Add(p)
+			}
+			for _, q := range drivers {
+				b.Add(q)
+			}
+
+			for _, maxDistance := range []Meters{Km(0.5), Km(5)} {
+				joined := make(map[joinPair]Meters)
+				Join(a, b, maxDistance, func(p, q Point, d Meters) {
+					_, seen := joined[joinPair{p.Id(), q.Id()}]
+					assert.False(t, seen)
+					joined[joinPair{p.Id(), q.Id()}] = d
+				})
+
+				assert.Equal(t, bruteForceJoin(requests, drivers, maxDistance), joined)
+			}
+		}
+	}
+}
+
+func TestKNearestJoin(t *testing.T) {
+	rand.Seed(13)
+
+	requests := randomPoints(300, randomPoint)
+	drivers := randomPoints(2000, randomPoint)
+
+	a := NewPointsIndex(Km(2))
+	b := NewPointsIndex(Km(0.5))
+	for _, p := range requests {
+		a.Add(p)
+	}
+	for _, q := range drivers {
+		b.Add(q)
+	}
+
+	joined := make(map[string][]Point)
+	KNearestJoin(a, b, 3, Km(1), func(p, q Point, d Meters) {
+		assert.Equal(t, Distance(p, q), d)
+		joined[p.Id()] = append(joined[p.Id()], q)
+	})
+
+	for _, p := range requests {
+		expected := b.KNearest(p, 3, Km(1), all)
+		if len(expected) == 0 {
+			assert.NotContains(t, joined, p.Id())
+		} else {
+			assert.Equal(t, expected, joined[p.Id()])
+		}
+	}
+}
+
+func TestJoinSelf(t *testing.T) {
+	index := NewPointsIndex(Km(0.5))
+	for _, station := range tubeStations() {
+		index.Add(station)
+	}
+
+	pairs := 0
+	Join(index, index, Meters(0), func(p, q Point, d Meters) {
+		assert.Equal(t, p.Id(), q.Id())
+		pairs++
+	})
+	assert.Equal(t, len(index.GetAll()), pairs)
+
+	KNearestJoin(index, NewPointsIndex(Km(0.5)), 5, Km(1), func(p, q Point, d Meters) {
+		assert.Fail(t, "joined with an empty index")
+	})
+}
+
+func TestReverseKNearest(t *testing.T) {
+	rand.Seed(24)
+
+	requests := randomPoints(1000, randomPoint)
+	drivers := randomPoints(500, randomPoint)
+
+	requestsIndex := NewPointsIndex(Km(0.5))
+	driversIndex := NewPointsIndex(Km(1))
+	for _, p := range requests {
+		requestsIndex.Add(p)
+	}
+	for _, q := range drivers {
+		driversIndex.Add(q)
+	}
+
+	found := 0
+	for _, k := range []int{1, 3} {
+		for _, maxDistance := range []Meters{Km(1), Km(5)} {
+			for _, driver := range drivers[0:20] {
+				expected := make([]Point, 0)
+				for _, p := range requests {
+					for _, nearest := range driversIndex.KNearest(p, k, maxDistance, all) {
+						if nearest.Id() == driver.Id() {
+							expected = append(expected, p)
+						}
+					}
+				}
+
+				reverse := ReverseKNearest(driversIndex, requestsIndex, driver, k, maxDistance)
+				assert.ElementsMatch(t, expected, reverse)
+				found += len(reverse)
+
+				for i := 1; i < len(reverse); i++ {
+					assert.True(t, Distance(driver, reverse[i-1]) <= Distance(driver, reverse[i]))
+				}
+			}
+		}
+	}
+	assert.True(t, found > 0)
+}
+
+func TestReverseKNearestNewDriver(t *testing.T) {
+	requests := NewPointsIndex(Km(0.5))
+	for _, station := range tubeStations() {
+		requests.Add(station)
+	}
+
+	drivers := NewPointsIndex(Km(0.5))
+	drivers.Add(&GeoPoint{"driver", embankment.Lat(), embankment.Lon()})
+
+	// a driver that is not in the index yet, at Charing Cross
+	driver := &GeoPoint{"new driver", charring.Lat(), charring.Lon()}
+
+	reverse := ReverseKNearest(drivers, requests, driver, 1, Km(0.3))
+	assert.Equal(t, charring, reverse[0])
+	assert.NotContains(t, reverse, embankment)
+	assert.Contains(t, ReverseKNearest(drivers, requests, driver, 2, Km(0.3)), embankment)
+
+	assert.Empty(t, ReverseKNearest(drivers, requests, driver, 0, Km(0.3)))
+	assert.Empty(t, ReverseKNearest(drivers, NewPointsIndex(Km(0.5)), driver, 1, Km(0.3)))
+}
@@ -0,0 +1,94 @@
+package geoindex
+
+import (
+	"container/heap"
+	"math"
+	"sort"
+)
+
+// KBest returns the k points within maxDistance of point with the lowest score, sorted by score. Points with an
+// infinite score are excluded. When bound is not nil, bound(d) must be a lower bound of the score of any point at
+// distance d or farther, then the search stops once the k best points found score better than any point that was
+// not visited yet. Without bound all the points within maxDistance are scored.
+func (points *PointsIndex) KBest(point Point, k int, maxDistance Meters, score func(p Point, d Meters) float64, bound func(d Meters) float64) []Point {
+	best := make(scoredHeap, 0, k)
+	if k <= 0 {
+		return best.points()
+	}
+
+	offer := func(candidate Point) bool {
+		if dist := Distance(point, candidate); dist <= maxDistance {
+			if s := score(candidate, dist); !math.IsInf(s, 1) {
+				best.offer(scoredPoint{candidate, s}, k)
+			}
+		}
+		return true
+	}
+
+	radius := func(searched Meters) Meters {
+		// the points that were not visited are farther than searched
+		if bound != nil && len(best) == k && best[0].score <= bound(searched) {
+			return searched
+		}
+		return points.index.growRadius(searched)
+	}
+
+	points.index.VisitExpanding(point, maxDistance, visitPoints(anyPoint, offer), radius)
+
+	return best.points()
+}
+
+// A point with its score, lower is better.
+type scoredPoint struct {
+	point Point
+	score float64
+}
+
+// scoredHeap is a max heap of points by score, so the worst of the best points found is at the top.
+type scoredHeap []scoredPoint
+
+func (h scoredHeap) Len() int {
+	return len(h)
+}
+
+func (h scoredHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+}
+
+func (h scoredHeap) Less(i, j int) bool {
+	return h[i].score > h[j].score
+}
+
+func (h *scoredHeap) Push(x interface{}) {
+	*h = append(*h, x.(scoredPoint))
+}
+
+func (h *scoredHeap) Pop() interface{} {
+	old := *h
+	x := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return x
+}
+
+// offer adds candidate to the heap if it has less than k points or candidate scores better than the worst of them,
+// which is removed.
+func (h *scoredHeap) offer(candidate scoredPoint, k int) {
+	if len(*h) < k {
+		heap.Push(h, candidate)
+	} else if candidate.score < (*h)[0].score {
+		(*h)[0] = candidate
+		heap.Fix(h, 0)
+	}
+}
+
+// points returns the points of the heap sorted by score.
+func (h scoredHeap) points() []Point {
+	sort.Sort(sort.Reverse(h))
+
+	result := make([]Point, len(h))
+	for i, scored := range h {
+		result[i] = scored.point
+	}
+
+	return result
+}
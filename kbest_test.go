@@ -0,0 +1,84 @@
+package geoindex
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type ratedDriver struct {
+	*GeoPoint
+	rating float64
+}
+
+func TestKBest(t *testing.T) {
+	rand.Seed(22)
+
+	drivers := make([]Point, 0)
+	for i, p := range pointsAround(charring, 3000, 0.1) {
+		drivers = append(drivers, &ratedDriver{NewGeoPoint(strconv.Itoa(i), p.Lat(), p.Lon()), 1 + rand.Float64()*4})
+	}
+
+	index := NewPointsIndex(Km(0.5))
+	for _, driver := range drivers {
+		index.Add(driver)
+	}
+
+	// a 5 star driver counts as the distance, a 1 star driver as twice the distance
+	scored := 0
+	score := func(p Point, d Meters) float64 {
+		scored++
+		return float64(d) * (1 + (5-p.(*ratedDriver).rating)/4)
+	}
+	bound := func(d Meters) float64 {
+		return float64(d)
+	}
+
+	for _, k := range []int{1, 5, 20} {
+		for _, maxDistance := range []Meters{Km(0.5), Km(3), Km(20)} {
+			within := bruteForceWithin(drivers, charring, maxDistance+1)
+			sort.SliceStable(within, func(i, j int) bool {
+				return score(within[i], Distance(charring, within[i])) < score(within[j], Distance(charring, within[j]))
+			})
+			expected := within[0:min(k, len(within))]
+
+			scored = 0
+			assert.Equal(t, expected, index.KBest(charring, k, maxDistance, score, bound))
+			withBound := scored
+
+			scored = 0
+			assert.Equal(t, expected, index.KBest(charring, k, maxDistance, score, nil))
+			withoutBound := scored
+
+			assert.True(t, withBound <= withoutBound)
+			if maxDistance == Km(20) {
+				assert.True(t, withBound < withoutBound/10)
+			}
+		}
+	}
+}
+
+func TestKBestExcluded(t *testing.T) {
+	index := NewPointsIndex(Km(0.5))
+	for _, station := range tubeStations() {
+		index.Add(station)
+	}
+
+	noEmbankment := func(p Point, d Meters) float64 {
+		if p.Id() == embankment.Id() {
+			return math.Inf(1)
+		}
+		return float64(d)
+	}
+	distance := func(d Meters) float64 {
+		return float64(d)
+	}
+
+	assert.Equal(t, []Point{charring, leicester, coventGarden}, index.KBest(charring, 3, Km(5), noEmbankment, distance))
+	assert.Empty(t, index.KBest(charring, 0, Km(5), noEmbankment, distance))
+	assert.Empty(t, index.KBest(&GeoPoint{"", 0, 0}, 3, Km(5), noEmbankment, distance))
+}
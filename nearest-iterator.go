@@ -0,0 +1,74 @@
+package geoindex
+
+import (
+	"container/heap"
+)
+
+// NearestIterator iterates the points of a PointsIndex in increasing distance from a point. It searches the cells
+// around the point in growing circles only as far as needed to find the next point, so stopping early is cheap.
+// Points added to or removed from the index while iterating may or may not be returned.
+type NearestIterator struct {
+	index       *geoIndex
+	point       Point
+	maxDistance Meters
+	search      *circleSearch
+	// the points found but not returned yet, the nearest at the top
+	candidates byDistance
+	current    PointDistance
+}
+
+// Nearest returns an iterator over the points within maxDistance of point, nearest first.
+//
+//	nearest := index.Nearest(point, Km(5))
+//	for nearest.Next() {
+//		driver := nearest.Point()
+//	}
+func (points *PointsIndex) Nearest(point Point, maxDistance Meters) *NearestIterator {
+	return &NearestIterator{
+		index:       points.index,
+		point:       point,
+		maxDistance: maxDistance,
+		search:      points.index.newCircleSearch(point, maxDistance),
+		candidates:  make(byDistance, 0),
+	}
+}
+
+// Next advances the iterator to the next nearest point and returns false when there are no more points.
+func (it *NearestIterator) Next() bool {
+	// the candidates within the radius searched are nearer than the points that were not found yet
+	for len(it.candidates) == 0 || it.candidates[0].Distance > it.search.searched {
+		if !it.expand() {
+			break
+		}
+	}
+
+	if len(it.candidates) == 0 {
+		it.current = PointDistance{}
+		return false
+	}
+
+	it.current = heap.Pop(&it.candidates).(PointDistance)
+	return true
+}
+
+// Point returns the current point.
+func (it *NearestIterator) Point() Point {
+	return it.current.Point
+}
+
+// Distance returns the distance to the current point.
+func (it *NearestIterator) Distance() Meters {
+	return it.current.Distance
+}
+
+// expand searches the next circle around the point and returns false when there is nothing left to search.
+func (it *NearestIterator) expand() bool {
+	return it.search.next(it.index.growRadius(it.search.searched), visitPoints(anyPoint, it.add))
+}
+
+func (it *NearestIterator) add(nearbyPoint Point) bool {
+	if dist := Distance(it.point, nearbyPoint); dist <= it.maxDistance {
+		heap.Push(&it.candidates, PointDistance{Point: nearbyPoint, Distance: dist})
+	}
+	return true
+}
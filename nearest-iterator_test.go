@@ -0,0 +1,84 @@
+package geoindex
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNearestIterator(t *testing.T) {
+	index := NewPointsIndex(Km(0.5))
+	for _, point := range tubeStations() {
+		index.Add(point)
+	}
+
+	nearest := index.Nearest(charring, Km(1))
+	found := make([]Point, 0)
+	for nearest.Next() {
+		assert.Equal(t, Distance(charring, nearest.Point()), nearest.Distance())
+		found = append(found, nearest.Point())
+	}
+
+	assert.Equal(t, index.KNearest(charring, 100, Km(1), all), found)
+	assert.False(t, nearest.Next())
+	assert.Nil(t, nearest.Point())
+}
+
+func TestNearestIteratorStop(t *testing.T) {
+	index := NewPointsIndex(Km(0.5))
+	for _, point := range tubeStations() {
+		index.Add(point)
+	}
+
+	nearest := index.Nearest(charring, Km(50))
+	var accepted Point
+	for nearest.Next() {
+		if nearest.Point().Id() == picadilly.Id() {
+			accepted = nearest.Point()
+			break
+		}
+	}
+
+	assert.Equal(t, picadilly, accepted)
+	// only the circle up to the accepted point has been searched
+	assert.True(t, nearest.search.searched < 2*Distance(charring, picadilly)+index.index.scheme.size())
+}
+
+func TestNearestIteratorBruteForce(t *testing.T) {
+	rand.Seed(7)
+
+	for _, generatePoint := range []func() Point{randomPoint, randomPointWorldWide} {
+		points := make([]Point, 1000)
+		for i := range points {
+			points[i] = generatePoint()
+		}
+
+		for _, index := range []*PointsIndex{NewPointsIndex(Km(1)), NewQuadtreePointsIndex(16)} {
+			for _, p := range points {
+				index.Add(p)
+			}
+
+			for i := 0; i < 10; i++ {
+				center := generatePoint()
+				expected := bruteForceNearest(points, center, len(points), Km(100))
+
+				found := make([]Point, 0)
+				for nearest := index.Nearest(center, Km(100)); nearest.Next(); {
+					found = append(found, nearest.Point())
+				}
+
+				assert.Equal(t, expected, found)
+			}
+		}
+	}
+}
+
+func TestNearestIteratorEmpty(t *testing.T) {
+	index := NewPointsIndex(Km(0.5))
+	assert.False(t, index.Nearest(charring, Km(100)).Next())
+
+	index.Add(charring)
+	nearest := index.Nearest(oxford, Km(0.1))
+	assert.False(t, nearest.Next())
+}
@@ -0,0 +1,80 @@
+package geoindex
+
+import (
+	"math"
+	"sort"
+)
+
+// NearestPerCategory returns the kPerCategory nearest points within maxDistance of point in each category, sorted by
+// distance. category returns the category of a point, or "" to exclude it. When categories are given only the points
+// in them are returned and the search stops as soon as each of them has its kPerCategory nearest points, otherwise
+// every category found within maxDistance is returned. The points are visited once for all the categories.
+func (points *PointsIndex) NearestPerCategory(point Point, maxDistance Meters, category func(Point) string, kPerCategory int, categories ...string) map[string][]Point {
+	nearest := make(map[string]*nearestHeap, len(categories))
+	for _, name := range categories {
+		nearest[name] = &nearestHeap{}
+	}
+
+	if kPerCategory > 0 {
+		points.nearestPerCategory(point, maxDistance, category, kPerCategory, nearest, len(categories) > 0)
+	}
+
+	result := make(map[string][]Point, len(nearest))
+	for name, categoryNearest := range nearest {
+		sort.Sort(byDistance(*categoryNearest))
+
+		result[name] = make([]Point, len(*categoryNearest))
+		for i, nearby := range *categoryNearest {
+			result[name][i] = nearby.Point
+		}
+	}
+
+	return result
+}
+
+// nearestPerCategory fills nearest with the k nearest points of each category. When fixed is true only the categories
+// already in nearest are filled.
+func (points *PointsIndex) nearestPerCategory(point Point, maxDistance Meters, category func(Point) string, k int, nearest map[string]*nearestHeap, fixed bool) {
+	offer := func(nearbyPoint Point) bool {
+		name := category(nearbyPoint)
+		if name == "" {
+			return true
+		}
+
+		categoryNearest, ok := nearest[name]
+		if !ok && fixed {
+			return true
+		}
+
+		dist := Distance(point, nearbyPoint)
+		if dist > maxDistance {
+			return true
+		}
+
+		if !ok {
+			categoryNearest = &nearestHeap{}
+			nearest[name] = categoryNearest
+		}
+
+		categoryNearest.offer(PointDistance{Point: nearbyPoint, Distance: dist}, k)
+		return true
+	}
+
+	// once all the categories are full only the points nearer than the farthest of their points are left to find
+	radius := func(searched Meters) Meters {
+		if !fixed {
+			return points.index.growRadius(searched)
+		}
+
+		farthest := Meters(0)
+		for _, categoryNearest := range nearest {
+			if len(*categoryNearest) < k {
+				return points.index.growRadius(searched)
+			}
+			farthest = Meters(math.Max(float64(farthest), float64((*categoryNearest)[0].Distance)))
+		}
+		return farthest
+	}
+
+	points.index.VisitExpanding(point, maxDistance, visitPoints(anyPoint, offer), radius)
+}
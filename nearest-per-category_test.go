@@ -0,0 +1,85 @@
+package geoindex
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var vehicleClasses = []string{"standard", "xl", "wheelchair"}
+
+func vehicleClass(p Point) string {
+	i, _ := strconv.Atoi(p.Id())
+	// wheelchair accessible vehicles are rare
+	if i%20 == 0 {
+		return "wheelchair"
+	}
+	return vehicleClasses[i%2]
+}
+
+func TestNearestPerCategory(t *testing.T) {
+	points := pointsAround(charring, 3000, 0.1)
+	index := NewPointsIndex(Km(0.5))
+	for _, p := range points {
+		index.Add(p)
+	}
+
+	for _, k := range []int{1, 3, 10} {
+		for _, maxDistance := range []Meters{Km(0.5), Km(3), Km(20)} {
+			byClass := make(map[string][]Point)
+			for _, p := range points {
+				byClass[vehicleClass(p)] = append(byClass[vehicleClass(p)], p)
+			}
+
+			expected := make(map[string][]Point)
+			for _, class := range vehicleClasses {
+				expected[class] = bruteForceNearest(byClass[class], charring, k, maxDistance)
+			}
+
+			assert.Equal(t, expected, index.NearestPerCategory(charring, maxDistance, vehicleClass, k, vehicleClasses...))
+
+			found := index.NearestPerCategory(charring, maxDistance, vehicleClass, k)
+			for class, nearest := range expected {
+				if len(nearest) == 0 {
+					delete(expected, class)
+				}
+			}
+			assert.Equal(t, expected, found)
+		}
+	}
+}
+
+func TestNearestPerCategoryRequested(t *testing.T) {
+	index := NewPointsIndex(Km(0.5))
+	for _, station := range tubeStations() {
+		index.Add(station)
+	}
+
+	line := func(p Point) string {
+		switch p.Id() {
+		case embankment.Id(), leicester.Id():
+			return "northern"
+		case coventGarden.Id():
+			return "piccadilly"
+		}
+		return ""
+	}
+
+	assert.Equal(t, map[string][]Point{
+		"northern":   {embankment},
+		"piccadilly": {coventGarden},
+		"victoria":   {},
+	}, index.NearestPerCategory(charring, Km(5), line, 1, "northern", "piccadilly", "victoria"))
+
+	assert.Equal(t, map[string][]Point{
+		"northern": {embankment, leicester},
+	}, index.NearestPerCategory(charring, Km(5), line, 5, "northern"))
+
+	assert.Equal(t, map[string][]Point{
+		"northern":   {embankment, leicester},
+		"piccadilly": {coventGarden},
+	}, index.NearestPerCategory(charring, Km(5), line, 2))
+
+	assert.Equal(t, map[string][]Point{"northern": {}}, index.NearestPerCategory(charring, Km(5), line, 0, "northern"))
+}
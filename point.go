@@ -55,10 +55,26 @@ func (p *GeoPoint) Lon() float64 {
 	return p.Plon
 }
 
+// PointDistance is a point with its distance, bearing and direction from another point.
+type PointDistance struct {
+	Point     Point
+	Distance  Meters
+	Bearing   float64
+	Direction Direction
+}
+
+// newPointDistance returns p2 with its distance, bearing and direction from p1.
+func newPointDistance(p1, p2 Point) PointDistance {
+	bearing := BearingTo(p1, p2)
+	return PointDistance{p2, Distance(p1, p2), bearing, directionOf(bearing)}
+}
+
 // DirectionTo returns the direction from p1 to p2
 func DirectionTo(p1, p2 Point) Direction {
-	bearing := BearingTo(p1, p2)
+	return directionOf(BearingTo(p1, p2))
+}
 
+func directionOf(bearing float64) Direction {
 	index := bearing - 22.5
 
 	if index < 0 {
@@ -109,32 +125,51 @@ func distance(p1, p2 Point) Meters {
 	return Meters(dist)
 }
 
-type lonDegreeDistance map[int]Meters
+// lonDelta returns the difference between two longitudes in degrees, going the short way around the antimeridian.
+func lonDelta(lon1, lon2 float64) float64 {
+	delta := math.Mod(math.Abs(lon1-lon2), 360)
+	if delta > 180 {
+		delta = 360 - delta
+	}
+	return delta
+}
 
-func (lonDist lonDegreeDistance) get(lat float64) Meters {
-	latIndex := int(lat * 10)
-	latRounded := float64(latIndex) / 10
+// The length of a degree of longitude by latitude, rounded towards 0 to a tenth of a degree. The table is filled
+// once and only read afterwards, so the indexes can be queried concurrently.
+type lonDegreeDistance [1801]Meters
 
-	if value, ok := lonDist[latIndex]; ok {
-		return value
-	} else {
-		dist := distance(&GeoPoint{"", latRounded, 0.0}, &GeoPoint{"", latRounded, 1.0})
-		lonDist[latIndex] = dist
-		return dist
+func newLonDegreeDistance() *lonDegreeDistance {
+	lonDist := &lonDegreeDistance{}
+	for i := range lonDist {
+		latRounded := float64(i-900) / 10
+		lonDist[i] = distance(&GeoPoint{"", latRounded, 0.0}, &GeoPoint{"", latRounded, 1.0})
 	}
+
+	return lonDist
+}
+
+func (lonDist *lonDegreeDistance) get(lat float64) Meters {
+	latIndex := int(normalizeLat(lat) * 10)
+	return lonDist[latIndex+900]
 }
 
 var (
-	lonLength = lonDegreeDistance{}
+	lonLength = newLonDegreeDistance()
 )
 
 // Calculates approximate distance between two points using euclidian distance. The assumption here
-// is that the points are relatively close to each other.
+// is that the points are relatively close to each other. Close to the poles the approximation breaks
+// down, so the square of the exact distance is returned instead.
 func approximateSquareDistance(p1, p2 Point) Meters {
+	if math.Abs(p1.Lat()) > polarLat || math.Abs(p2.Lat()) > polarLat {
+		d := distance(p1, p2)
+		return d * d
+	}
+
 	avgLat := (p1.Lat() + p2.Lat()) / 2.0
 
 	latLen := math.Abs(p1.Lat()-p2.Lat()) * float64(latDegreeLength)
-	lonLen := math.Abs(p1.Lon()-p2.Lon()) * float64(lonLength.get(avgLat))
+	lonLen := lonDelta(p1.Lon(), p2.Lon()) * float64(lonLength.get(avgLat))
 
 	return Meters(latLen*latLen + lonLen*lonLen)
 }
@@ -4,8 +4,9 @@
 package geoindex
 
 import (
-	"math"
+	"container/heap"
 	"sort"
+	"strings"
 )
 
 // A geoindex that stores points.
@@ -16,15 +17,26 @@ type PointsIndex struct {
 
 // NewPointsIndex creates new PointsIndex that maintains the points in each cell.
 func NewPointsIndex(resolution Meters) *PointsIndex {
+	return NewSchemePointsIndex(NewGridScheme(resolution))
+}
+
+// NewSchemePointsIndex creates new PointsIndex that maintains the points in each cell of scheme.
+func NewSchemePointsIndex(scheme CellScheme) *PointsIndex {
 	newSet := func() interface{} {
 		return newSet()
 	}
 
-	return &PointsIndex{newGeoIndex(resolution, newSet), make(map[string]Point)}
+	return &PointsIndex{newSchemeGeoIndex(scheme.scheme, newSet), make(map[string]Point)}
 }
 
 // NewExpiringPointsIndex creates new PointIndex that expires the points in each cell after expiration minutes.
 func NewExpiringPointsIndex(resolution Meters, expiration Minutes) *PointsIndex {
+	return NewExpiringSchemePointsIndex(NewGridScheme(resolution), expiration)
+}
+
+// NewExpiringSchemePointsIndex creates new PointIndex that expires the points in each cell of scheme after
+// expiration minutes.
+func NewExpiringSchemePointsIndex(scheme CellScheme, expiration Minutes) *PointsIndex {
 	currentPosition := make(map[string]Point)
 
 	newExpiringSet := func() interface{} {
@@ -38,7 +50,7 @@ func NewExpiringPointsIndex(resolution Meters, expiration Minutes) *PointsIndex
 		return set
 	}
 
-	return &PointsIndex{newGeoIndex(resolution, newExpiringSet), currentPosition}
+	return &PointsIndex{newSchemeGeoIndex(scheme.scheme, newExpiringSet), currentPosition}
 }
 
 func (pi *PointsIndex) Clone() *PointsIndex {
@@ -82,6 +94,7 @@ func (points *PointsIndex) Add(point Point) {
 	points.Remove(point.Id())
 	newSet := points.index.AddEntryAt(point).(set)
 	newSet.Add(point.Id(), point)
+	points.index.Added(point)
 	points.currentPosition[point.Id()] = point
 }
 
@@ -90,6 +103,7 @@ func (points *PointsIndex) Remove(id string) {
 	if prevPoint, ok := points.currentPosition[id]; ok {
 		set := points.index.GetEntryAt(prevPoint).(set)
 		set.Remove(prevPoint.Id())
+		points.index.Removed(prevPoint)
 		delete(points.currentPosition, prevPoint.Id())
 	}
 }
@@ -98,6 +112,15 @@ func between(value float64, min float64, max float64) bool {
 	return value >= min && value <= max
 }
 
+// betweenLon is like between, but wraps around the antimeridian when min > max.
+func betweenLon(value float64, min float64, max float64) bool {
+	value, min, max = normalizeLon(value), normalizeLon(min), normalizeLon(max)
+	if min > max {
+		return value >= min || value <= max
+	}
+	return between(value, min, max)
+}
+
 func getPoints(entries []interface{}, accept func(point Point) bool) []Point {
 	result := make([]Point, 0)
 	result = getPointsAppend(result, entries, accept)
@@ -118,32 +141,129 @@ func getPointsAppend(s []Point, entries []interface{}, accept func(point Point)
 	return s
 }
 
-// Range returns the points within the range defined by top left and bottom right.
+// visitPoints returns a visitor of index entries that calls visitor with the points of each entry that match the
+// accept criteria, until visitor returns false.
+func visitPoints(accept func(point Point) bool, visitor func(point Point) bool) func(entry interface{}) bool {
+	visitValue := func(value interface{}) bool {
+		point := value.(Point)
+		return !accept(point) || visitor(point)
+	}
+
+	return func(entry interface{}) bool {
+		return entry.(set).Visit(visitValue)
+	}
+}
+
+// anyPoint accepts every point.
+func anyPoint(_ Point) bool {
+	return true
+}
+
+// Range returns the points within the range defined by top left and bottom right. The range wraps around
+// the antimeridian when the longitude of topLeft is greater than the longitude of bottomRight.
 func (points *PointsIndex) Range(topLeft Point, bottomRight Point) []Point {
-	entries := points.index.Range(topLeft, bottomRight)
+	return points.AppendRange(make([]Point, 0), topLeft, bottomRight)
+}
+
+// AppendRange appends the points within the range defined by top left and bottom right to dst and returns the
+// extended slice.
+func (points *PointsIndex) AppendRange(dst []Point, topLeft Point, bottomRight Point) []Point {
+	points.RangeFunc(topLeft, bottomRight, func(point Point) bool {
+		dst = append(dst, point)
+		return true
+	})
+
+	return dst
+}
+
+// RangeFunc calls fn with each point within the range defined by top left and bottom right, without collecting
+// the points, until fn returns false.
+func (points *PointsIndex) RangeFunc(topLeft Point, bottomRight Point, fn func(point Point) bool) {
 	accept := func(point Point) bool {
-		return between(point.Lat(), bottomRight.Lat(), topLeft.Lat()) &&
-			between(point.Lon(), topLeft.Lon(), bottomRight.Lon())
+		return inRange(point, topLeft, bottomRight)
 	}
 
-	return getPoints(entries, accept)
+	points.index.VisitRange(topLeft, bottomRight, visitPoints(accept, fn))
 }
 
-type sortedPoints struct {
-	points []Point
-	point  Point
+func inRange(point Point, topLeft Point, bottomRight Point) bool {
+	return between(point.Lat(), bottomRight.Lat(), topLeft.Lat()) &&
+		betweenLon(point.Lon(), topLeft.Lon(), bottomRight.Lon())
 }
 
-func (p *sortedPoints) Len() int {
-	return len(p.points)
+// nearestHeap is a max heap of points by distance, so the farthest of the nearest points found is at the top.
+type nearestHeap []PointDistance
+
+func (h nearestHeap) Len() int {
+	return len(h)
 }
 
-func (p *sortedPoints) Swap(i, j int) {
-	p.points[i], p.points[j] = p.points[j], p.points[i]
+func (h nearestHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
 }
 
-func (p *sortedPoints) Less(i, j int) bool {
-	return approximateSquareDistance(p.points[i], p.point) < approximateSquareDistance(p.points[j], p.point)
+func (h nearestHeap) Less(i, j int) bool {
+	return h[i].Distance > h[j].Distance
+}
+
+func (h *nearestHeap) Push(x interface{}) {
+	*h = append(*h, x.(PointDistance))
+}
+
+func (h *nearestHeap) Pop() interface{} {
+	old := *h
+	x := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return x
+}
+
+// offer adds candidate to the heap if it has less than k points or candidate is nearer than the farthest of them,
+// which is removed.
+func (h *nearestHeap) offer(candidate PointDistance, k int) {
+	if len(*h) < k {
+		heap.Push(h, candidate)
+	} else if candidate.Distance < (*h)[0].Distance {
+		(*h)[0] = candidate
+		heap.Fix(h, 0)
+	}
+}
+
+// byDistance sorts points by distance and is a min heap of points by distance.
+type byDistance []PointDistance
+
+func (p byDistance) Len() int {
+	return len(p)
+}
+
+func (p byDistance) Swap(i, j int) {
+	p[i], p[j] = p[j], p[i]
+}
+
+func (p byDistance) Less(i, j int) bool {
+	return p[i].Distance < p[j].Distance
+}
+
+func (p *byDistance) Push(x interface{}) {
+	*p = append(*p, x.(PointDistance))
+}
+
+func (p *byDistance) Pop() interface{} {
+	old := *p
+	x := old[len(old)-1]
+	*p = old[:len(old)-1]
+	return x
+}
+
+// pointDistances returns the distance and the direction from point to each of points, sorted by distance.
+func pointDistances(point Point, points []Point) []PointDistance {
+	result := make([]PointDistance, len(points))
+	for i, p := range points {
+		result[i] = newPointDistance(point, p)
+	}
+
+	sort.Stable(byDistance(result))
+
+	return result
 }
 
 func min(a, b int) int {
@@ -154,70 +274,219 @@ func min(a, b int) int {
 	}
 }
 
-// KNearest returns the k nearest points near point within maxDistance that match the accept criteria.
+// KNearest returns the k nearest points near point within maxDistance that match the accept criteria, sorted by
+// distance.
 func (points *PointsIndex) KNearest(point Point, k int, maxDistance Meters, accept func(p Point) bool) []Point {
-	nearbyPoints := make([]Point, 0)
-	pointEntry := points.index.GetEntryAt(point).(set)
-	nearbyPoints = append(nearbyPoints, getPoints([]interface{}{pointEntry}, accept)...)
+	nearest := points.nearest(point, k, maxDistance, accept)
 
-	totalCount := 0
-	idx := cellOf(point, points.index.resolution)
-	// Explicitely assign a greater max distance so that we definitely return enough points
-	// and make sure it searches at least one square away.
-	coarseMaxDistance := math.Max(float64(maxDistance)*2.0, float64(points.index.resolution)*2.0+0.01)
-
-	for d := 1; float64(d)*float64(points.index.resolution) <= coarseMaxDistance; d++ {
-		oldCount := len(nearbyPoints)
+	result := make([]Point, len(nearest))
+	for i, nearbyPoint := range nearest {
+		result[i] = nearbyPoint.Point
+	}
 
-		nearbyPoints = getPointsAppend(nearbyPoints, points.index.get(idx.x-d, idx.x+d, idx.y+d, idx.y+d), accept)
-		nearbyPoints = getPointsAppend(nearbyPoints, points.index.get(idx.x-d, idx.x+d, idx.y-d, idx.y-d), accept)
-		nearbyPoints = getPointsAppend(nearbyPoints, points.index.get(idx.x-d, idx.x-d, idx.y-d+1, idx.y+d-1), accept)
-		nearbyPoints = getPointsAppend(nearbyPoints, points.index.get(idx.x+d, idx.x+d, idx.y-d+1, idx.y+d-1), accept)
+	return result
+}
 
-		totalCount += len(nearbyPoints) - oldCount
+// KNearestWithDistance is like KNearest, but returns the distance and the direction from point to each of the
+// points.
+func (points *PointsIndex) KNearestWithDistance(point Point, k int, maxDistance Meters, accept func(p Point) bool) []PointDistance {
+	nearest := points.nearest(point, k, maxDistance, accept)
 
-		if totalCount > k {
-			break
-		}
+	for i, nearbyPoint := range nearest {
+		nearest[i] = newPointDistance(point, nearbyPoint.Point)
 	}
 
-	sortedPoints := &sortedPoints{nearbyPoints, point}
-	sort.Sort(sortedPoints)
+	return nearest
+}
+
+// nearest returns the k nearest points within maxDistance that match the accept criteria with their distances,
+// sorted by distance. It searches the cells around point in growing circles and keeps the k nearest points seen so
+// far in a heap. The points that were not seen are farther than the radius searched, so the search stops once the
+// kth nearest point is within it. Until there are k points the radius doubles, then it grows to the distance of
+// the kth nearest point.
+func (points *PointsIndex) nearest(point Point, k int, maxDistance Meters, accept func(p Point) bool) []PointDistance {
+	nearest := make(nearestHeap, 0, k)
+	if k <= 0 {
+		return nearest
+	}
 
-	k = min(k, len(sortedPoints.points))
+	offer := func(nearbyPoint Point) bool {
+		if dist := Distance(point, nearbyPoint); dist <= maxDistance {
+			nearest.offer(PointDistance{Point: nearbyPoint, Distance: dist}, k)
+		}
+		return true
+	}
 
-	// filter points which longer than maxDistance away from point.
-	for i, nearbyPoint := range sortedPoints.points {
-		if Distance(point, nearbyPoint) > maxDistance || i == k {
-			k = i
-			break
+	// once k points are found only the points nearer than the farthest of them are left to find
+	radius := func(searched Meters) Meters {
+		if len(nearest) == k {
+			return nearest[0].Distance
 		}
+		return points.index.growRadius(searched)
 	}
 
-	return sortedPoints.points[0:k]
+	points.index.VisitExpanding(point, maxDistance, visitPoints(accept, offer), radius)
+
+	sort.Sort(byDistance(nearest))
+
+	return nearest
 }
 
 // PointsWithin returns all points with distance of point that match the accept criteria.
 func (points *PointsIndex) PointsWithin(point Point, distance Meters, accept func(p Point) bool) []Point {
+	return points.AppendPointsWithin(make([]Point, 0), point, distance, accept)
+}
+
+// AppendPointsWithin appends the points within distance of point that match the accept criteria to dst and returns
+// the extended slice.
+func (points *PointsIndex) AppendPointsWithin(dst []Point, point Point, distance Meters, accept func(p Point) bool) []Point {
+	points.PointsWithinFunc(point, distance, func(withinPoint Point) bool {
+		if accept(withinPoint) {
+			dst = append(dst, withinPoint)
+		}
+		return true
+	})
+
+	return dst
+}
+
+// PointsWithinFunc calls fn with each point within distance of point, without collecting the points, until fn
+// returns false.
+func (points *PointsIndex) PointsWithinFunc(point Point, distance Meters, fn func(p Point) bool) {
+	within := func(nearbyPoint Point) bool {
+		return Distance(point, nearbyPoint) < distance
+	}
 
-	d := int(distance / points.index.resolution)
+	points.index.VisitAround(point, distance, visitPoints(within, fn))
+}
 
-	if d == 0 {
-		d = 1
+// PointsBetween returns all points at least minDistance and less than maxDistance away from point that match the
+// accept criteria. The cells entirely within minDistance are skipped.
+func (points *PointsIndex) PointsBetween(point Point, minDistance Meters, maxDistance Meters, accept func(p Point) bool) []Point {
+	between := func(nearbyPoint Point) bool {
+		dist := Distance(point, nearbyPoint)
+		return dist >= minDistance && dist < maxDistance && accept(nearbyPoint)
 	}
 
-	idx := cellOf(point, points.index.resolution)
+	betweenPoints := make([]Point, 0)
+	points.index.VisitBetween(point, minDistance, maxDistance, visitPoints(between, func(betweenPoint Point) bool {
+		betweenPoints = append(betweenPoints, betweenPoint)
+		return true
+	}))
 
-	nearbyPoints := make([]Point, 0)
-	nearbyPoints = getPointsAppend(nearbyPoints, points.index.get(idx.x-d, idx.x+d, idx.y-d, idx.y+d), accept)
+	return betweenPoints
+}
 
-	// filter points which longer than maxDistance away from point.
-	withinPoints := make([]Point, 0)
-	for _, nearbyPoint := range nearbyPoints {
-		if Distance(point, nearbyPoint) < distance {
-			withinPoints = append(withinPoints, nearbyPoint)
-		}
+// PointsWithinWithDistance is like PointsWithin, but returns the distance and the direction from point to each of
+// the points, which are sorted by their exact distance.
+func (points *PointsIndex) PointsWithinWithDistance(point Point, distance Meters, accept func(p Point) bool) []PointDistance {
+	nearbyPoints := pointDistances(point, getPoints(points.index.Around(point, distance), accept))
+
+	// filter points which longer than distance away from point.
+	within := sort.Search(len(nearbyPoints), func(i int) bool { return nearbyPoints[i].Distance >= distance })
+
+	return nearbyPoints[0:within]
+}
+
+// WithinGeohash returns all points within the area of geohash.
+func (points *PointsIndex) WithinGeohash(geohash string) []Point {
+	geohash = strings.ToLower(geohash)
+
+	topLeft, bottomRight, ok := geohashBounds(geohash)
+	if !ok {
+		return make([]Point, 0)
+	}
+
+	accept := func(point Point) bool {
+		return GeohashOf(point, len(geohash)) == geohash
+	}
+
+	return getPoints(points.index.Range(topLeft, bottomRight), accept)
+}
+
+// InCell returns all points within the cell id. The cell can be at any level, regardless of the scheme of the index.
+func (points *PointsIndex) InCell(id CellID) []Point {
+	topLeft, bottomRight := id.Bounds()
+
+	accept := func(point Point) bool {
+		return id.Contains(CellIDOf(point, MaxCellLevel))
 	}
 
-	return withinPoints
+	return getPoints(points.index.Range(topLeft, bottomRight), accept)
+}
+
+// WithinPolygon returns all points within polygon that match the accept criteria.
+func (points *PointsIndex) WithinPolygon(polygon *Polygon, accept func(p Point) bool) []Point {
+	inside, boundary := points.index.Polygon(polygon)
+
+	withinPoints := getPoints(inside, accept)
+	return getPointsAppend(withinPoints, boundary, func(point Point) bool {
+		return polygon.Contains(point) && accept(point)
+	})
+}
+
+// CountRange returns the number of points within the range defined by top left and bottom right, like
+// len(Range(topLeft, bottomRight)). The points in cells entirely within the range are counted without visiting them.
+func (points *PointsIndex) CountRange(topLeft Point, bottomRight Point) int {
+	accept := func(point Point) bool {
+		return inRange(point, topLeft, bottomRight)
+	}
+
+	count := 0
+	points.index.VisitRangeInside(topLeft, bottomRight, func(entry interface{}, inside bool) bool {
+		count += countPoints(entry, inside, accept)
+		return true
+	})
+
+	return count
+}
+
+// CountWithin returns the number of points closer than distance to point, like len(PointsWithin(point, distance, all)).
+// The points in cells entirely within distance are counted without visiting them.
+func (points *PointsIndex) CountWithin(point Point, distance Meters) int {
+	within := func(withinPoint Point) bool {
+		return Distance(point, withinPoint) < distance
+	}
+
+	count := 0
+	points.index.VisitAroundInside(point, distance, func(entry interface{}, inside bool) bool {
+		count += countPoints(entry, inside, within)
+		return true
+	})
+
+	return count
+}
+
+// CountPolygon returns the number of points within polygon, like len(WithinPolygon(polygon, all)). The points in
+// cells entirely within the polygon are counted without visiting them.
+func (points *PointsIndex) CountPolygon(polygon *Polygon) int {
+	inside, boundary := points.index.Polygon(polygon)
+
+	count := 0
+	for _, entry := range inside {
+		count += countPoints(entry, true, nil)
+	}
+	for _, entry := range boundary {
+		count += countPoints(entry, false, polygon.Contains)
+	}
+
+	return count
+}
+
+// countPoints returns the size of the set entry when its cell is inside the query, otherwise the number of its points
+// that accept returns true for.
+func countPoints(entry interface{}, inside bool, accept func(point Point) bool) int {
+	if inside {
+		return entry.(set).Size()
+	}
+
+	count := 0
+	entry.(set).Visit(func(value interface{}) bool {
+		if accept(value.(Point)) {
+			count++
+		}
+		return true
+	})
+
+	return count
 }
@@ -2,6 +2,8 @@ package geoindex
 
 import (
 	"github.com/stretchr/testify/assert"
+	"math/rand"
+	"strconv"
 	"strings"
 	"testing"
 	"time"
@@ -11,42 +13,236 @@ var (
 	all = func(_ Point) bool { return true }
 )
 
+// pointsIndexes returns the points indexes that the tests run on, a grid with cells of size resolution and a quadtree
+// with small cells.
+func pointsIndexes(resolution Meters) []*PointsIndex {
+	return []*PointsIndex{NewPointsIndex(resolution), NewQuadtreePointsIndex(4)}
+}
+
 func TestRange(t *testing.T) {
-	index := NewPointsIndex(Km(1.0))
+	for _, index := range pointsIndexes(Km(1.0)) {
+		for _, point := range tubeStations() {
+			index.Add(point)
+		}
+
+		within := index.Range(oxford, embankment)
+		expected := []Point{picadilly, charring, coventGarden, embankment, leicester, oxford}
 
-	for _, point := range tubeStations() {
-		index.Add(point)
+		assert.True(t, pointsEqualIgnoreOrder(expected, within))
+		assert.Equal(t, charring, index.Get(charring.Id()))
+
+		for _, point := range points {
+			index.Remove(point.Id())
+		}
+		assert.Nil(t, index.Get(charring.Id()))
+
+		assert.Equal(t, len(index.Range(oxford, embankment)), 0)
 	}
+}
 
-	within := index.Range(oxford, embankment)
-	expected := []Point{picadilly, charring, coventGarden, embankment, leicester, oxford}
+func TestRangeFunc(t *testing.T) {
+	for _, index := range pointsIndexes(Km(1.0)) {
+		for _, point := range tubeStations() {
+			index.Add(point)
+		}
+
+		within := make([]Point, 0)
+		index.RangeFunc(oxford, embankment, func(point Point) bool {
+			within = append(within, point)
+			return true
+		})
+		assert.True(t, pointsEqualIgnoreOrder(index.Range(oxford, embankment), within))
+
+		visited := 0
+		index.RangeFunc(oxford, embankment, func(point Point) bool {
+			visited++
+			return visited < 2
+		})
+		assert.Equal(t, 2, visited)
+
+		dst := []Point{waterloo}
+		dst = index.AppendRange(dst, oxford, embankment)
+		assert.Equal(t, waterloo, dst[0])
+		assert.True(t, pointsEqualIgnoreOrder(within, dst[1:]))
+	}
+}
 
-	assert.True(t, pointsEqualIgnoreOrder(expected, within))
+func TestPointsWithinFunc(t *testing.T) {
+	for _, index := range pointsIndexes(Km(0.5)) {
+		for _, point := range tubeStations() {
+			index.Add(point)
+		}
+
+		within := make([]Point, 0)
+		index.PointsWithinFunc(charring, Km(1), func(point Point) bool {
+			within = append(within, point)
+			return true
+		})
+		assert.Equal(t, 9, len(within))
+		assert.True(t, pointsEqualIgnoreOrder(index.PointsWithin(charring, Km(1), all), within))
+
+		visited := 0
+		index.PointsWithinFunc(charring, Km(1), func(point Point) bool {
+			visited++
+			return false
+		})
+		assert.Equal(t, 1, visited)
+
+		noCharing := func(p Point) bool {
+			return p.Id() != charring.Id()
+		}
+		dst := index.AppendPointsWithin(make([]Point, 0, 16), charring, Km(1), noCharing)
+		assert.Equal(t, 8, len(dst))
+		assert.NotContains(t, dst, charring)
+	}
+}
 
-	for _, point := range points {
-		index.Remove(point.Id())
+func BenchmarkPointIndexRangeFunc(b *testing.B) {
+	index := NewPointsIndex(Km(0.5))
+	addStopTimer(index, 10000, randomPoint, bench(b))
+
+	count := 0
+	for i := 0; i < b.N; i++ {
+		index.RangeFunc(regentsPark, londonBridge, func(point Point) bool {
+			count++
+			return true
+		})
+	}
+}
+
+func TestCountRangeAndWithin(t *testing.T) {
+	points := append(pointsAround(charring, 5000, 0.1), pointsAround(&GeoPoint{"fiji", -17.5, 179.99}, 2000, 0.5)...)
+	points = append(points, pointsAround(&GeoPoint{"pole", 89.85, 0}, 500, 0.1)...)
+
+	for _, index := range []*PointsIndex{
+		NewPointsIndex(Km(0.5)),
+		NewPointsIndex(Meters(100)),
+		NewGeohashPointsIndex(6),
+		NewHexPointsIndex(Meters(300), 51.5),
+		NewHilbertPointsIndex(14),
+		NewQuadtreePointsIndex(8),
+	} {
+		for _, p := range points {
+			index.Add(p)
+		}
+
+		for _, box := range [][2]Point{
+			{&GeoPoint{"", 51.55, -0.2}, &GeoPoint{"", 51.46, -0.05}},
+			{&GeoPoint{"", -17.0, 179.8}, &GeoPoint{"", -18.0, -179.7}},
+			{&GeoPoint{"", 90, -180}, &GeoPoint{"", 89.85, 180}},
+			{&GeoPoint{"", -17.0, -179.7}, &GeoPoint{"", -18.0, 179.8}},
+		} {
+			assert.Equal(t, len(index.Range(box[0], box[1])), index.CountRange(box[0], box[1]))
+		}
+
+		for _, center := range []Point{charring, &GeoPoint{"", -17.5, 180}, &GeoPoint{"", 90, 0}} {
+			for _, distance := range []Meters{Meters(0), Km(1), Km(5), Km(30)} {
+				expected := bruteForceWithin(points, center, distance)
+				assert.Equal(t, len(expected), index.CountWithin(center, distance))
+			}
+		}
 	}
 
-	assert.Equal(t, len(index.Range(oxford, embankment)), 0)
+	assert.Equal(t, 0, NewPointsIndex(Km(0.5)).CountWithin(charring, Km(1)))
 }
 
-func TestKNearest(t *testing.T) {
+func TestCountRangeExpiring(t *testing.T) {
+	currentTime := time.Now()
+	now = currentTime
+
+	index := NewExpiringPointsIndex(Km(1.0), Minutes(5))
+	for _, station := range tubeStations() {
+		index.Add(station)
+	}
+
+	total := index.CountRange(oxford, embankment)
+	assert.Equal(t, len(index.Range(oxford, embankment)), total)
+	assert.True(t, total > 0)
+
+	now = currentTime.Add(10 * time.Minute)
+	assert.Equal(t, 0, index.CountRange(oxford, embankment))
+	assert.Equal(t, 0, index.CountWithin(charring, Km(5)))
+
+	now = time.Time{}
+}
+
+func BenchmarkPointIndexCountRange(b *testing.B) {
 	index := NewPointsIndex(Km(0.5))
+	for _, p := range pointsAround(charring, 100000, 0.1) {
+		index.Add(p)
+	}
 
-	for _, point := range tubeStations() {
-		index.Add(point)
+	topLeft, bottomRight := &GeoPoint{"", 51.55, -0.2}, &GeoPoint{"", 51.46, -0.05}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		index.CountRange(topLeft, bottomRight)
 	}
+}
+
+func TestKNearest(t *testing.T) {
+	for _, index := range pointsIndexes(Km(0.5)) {
+		for _, point := range tubeStations() {
+			index.Add(point)
+		}
 
-	assert.Equal(t, index.KNearest(charring, 3, Km(1), all), []Point{charring, embankment, leicester}, true)
-	assert.Equal(t, index.KNearest(charring, 5, Km(20), all), []Point{charring, embankment, leicester, coventGarden, picadilly}, true)
+		assert.Equal(t, index.KNearest(charring, 3, Km(1), all), []Point{charring, embankment, leicester}, true)
+		assert.Equal(t, index.KNearest(charring, 5, Km(20), all), []Point{charring, embankment, leicester, coventGarden, picadilly}, true)
 
-	noPicadilly := func(p Point) bool {
-		return !strings.Contains(p.Id(), "Piccadilly")
+		noPicadilly := func(p Point) bool {
+			return !strings.Contains(p.Id(), "Piccadilly")
+		}
+		assert.Equal(t, index.KNearest(charring, 5, Km(20), noPicadilly), []Point{charring, embankment, leicester, coventGarden, westminster}, true)
+
+		assert.Equal(t, index.KNearest(charring, 5, Km(20), all), []Point{charring, embankment, leicester, coventGarden, picadilly}, true)
+		assert.Equal(t, len(index.KNearest(charring, 100, Km(1), all)), 9)
 	}
-	assert.Equal(t, index.KNearest(charring, 5, Km(20), noPicadilly), []Point{charring, embankment, leicester, coventGarden, westminster}, true)
+}
+
+func TestKNearestWithDistance(t *testing.T) {
+	for _, index := range pointsIndexes(Km(0.5)) {
+		for _, point := range tubeStations() {
+			index.Add(point)
+		}
+
+		nearest := index.KNearestWithDistance(charring, 3, Km(1), all)
+		assert.Equal(t, 3, len(nearest))
+		assert.Equal(t, []Point{charring, embankment, leicester}, []Point{nearest[0].Point, nearest[1].Point, nearest[2].Point})
+
+		for _, n := range nearest {
+			assert.Equal(t, Distance(charring, n.Point), n.Distance)
+			assert.Equal(t, BearingTo(charring, n.Point), n.Bearing)
+			assert.Equal(t, DirectionTo(charring, n.Point), n.Direction)
+		}
+
+		assert.Equal(t, Meters(0), nearest[0].Distance)
+		assert.Equal(t, SouthEast, nearest[1].Direction)
+
+		assert.Equal(t, 9, len(index.KNearestWithDistance(charring, 100, Km(1), all)))
+		for _, n := range index.KNearestWithDistance(charring, 100, Km(1), all) {
+			assert.True(t, n.Distance <= Km(1))
+		}
+	}
+}
 
-	assert.Equal(t, index.KNearest(charring, 5, Km(20), all), []Point{charring, embankment, leicester, coventGarden, picadilly}, true)
-	assert.Equal(t, len(index.KNearest(charring, 100, Km(1), all)), 9)
+func TestPointsWithinWithDistance(t *testing.T) {
+	for _, index := range pointsIndexes(Km(0.5)) {
+		for _, point := range tubeStations() {
+			index.Add(point)
+		}
+
+		within := index.PointsWithinWithDistance(charring, Km(1), all)
+		assert.Equal(t, len(index.PointsWithin(charring, Km(1), all)), len(within))
+
+		for i, n := range within {
+			assert.True(t, n.Distance < Km(1))
+			assert.Equal(t, Distance(charring, n.Point), n.Distance)
+			if i > 0 {
+				assert.True(t, within[i-1].Distance <= n.Distance)
+			}
+		}
+
+		assert.Empty(t, index.PointsWithinWithDistance(&GeoPoint{"", 0, 0}, Km(1), all))
+	}
 }
 
 func TestExpiringIndex(t *testing.T) {
@@ -92,6 +288,10 @@ func BenchmarkPointIndexRange(b *testing.B) {
 	bench(b).CentralLondonRange(NewPointsIndex(Km(1.0)))
 }
 
+func BenchmarkPointIndexEuropeRange(b *testing.B) {
+	bench(b).EuropeRange(NewPointsIndex(Km(0.5)))
+}
+
 func BenchmarkPointIndexAdd(b *testing.B) {
 	bench(b).AddLondon(NewPointsIndex(Km(0.5)))
 }
@@ -135,3 +335,195 @@ func BenchmarkExpiringPointIndexRange(b *testing.B) {
 	expiration := Minutes(15)
 	bench(b).CentralLondonExpiringRange(NewExpiringPointsIndex(Km(0.5), expiration), expiration)
 }
+
+func pointsAround(center Point, n int, spread float64) []Point {
+	result := make([]Point, 0, n)
+	for i := 0; i < n; i++ {
+		lat := center.Lat() + rand.Float64()*spread*randSign()
+		lon := center.Lon() + rand.Float64()*spread*randSign()
+		result = append(result, &GeoPoint{center.Id() + strconv.Itoa(i), lat, lon})
+	}
+	return result
+}
+
+func bruteForceWithin(points []Point, point Point, distance Meters) []Point {
+	result := make([]Point, 0)
+	for _, p := range points {
+		if Distance(point, p) < distance {
+			result = append(result, p)
+		}
+	}
+	return result
+}
+
+func TestPointsWithinAtDifferentLatitudes(t *testing.T) {
+	for _, center := range []Point{
+		&GeoPoint{"Quito", -0.1807, -78.4678},
+		&GeoPoint{"London", 51.5074, -0.1278},
+		&GeoPoint{"Oslo", 59.9139, 10.7522},
+		&GeoPoint{"Tromso", 69.6492, 18.9553},
+	} {
+		points := pointsAround(center, 1000, 0.1)
+
+		for _, index := range pointsIndexes(Km(0.5)) {
+			for _, p := range points {
+				index.Add(p)
+			}
+
+			for _, distance := range []Meters{Km(0.3), Km(1), Km(3)} {
+				expected := bruteForceWithin(points, center, distance)
+				assert.True(t, pointsEqualIgnoreOrder(expected, index.PointsWithin(center, distance, all)))
+			}
+
+			assert.Equal(t, bruteForceNearest(points, center, 5, Km(5)), index.KNearest(center, 5, Km(5), all))
+		}
+	}
+}
+
+func bruteForceNearest(points []Point, point Point, k int, maxDistance Meters) []Point {
+	nearest := make([]Point, 0)
+	for _, nearbyPoint := range pointDistances(point, points) {
+		if len(nearest) < k && nearbyPoint.Distance <= maxDistance {
+			nearest = append(nearest, nearbyPoint.Point)
+		}
+	}
+	return nearest
+}
+
+func TestKNearestBruteForce(t *testing.T) {
+	rand.Seed(42)
+
+	for _, generatePoint := range []func() Point{randomPoint, randomPointWorldWide} {
+		points := make([]Point, 2000)
+		for i := range points {
+			points[i] = generatePoint()
+		}
+
+		for _, index := range []*PointsIndex{
+			NewPointsIndex(Km(0.5)),
+			NewPointsIndex(Km(5)),
+			NewHexPointsIndex(Km(1), 51.5),
+			NewHilbertPointsIndex(12),
+			NewQuadtreePointsIndex(16),
+		} {
+			for _, p := range points {
+				index.Add(p)
+			}
+
+			for i := 0; i < 10; i++ {
+				center := generatePoint()
+				for _, k := range []int{1, 5, 50} {
+					for _, maxDistance := range []Meters{Km(1), Km(20), Km(500)} {
+						expected := bruteForceNearest(points, center, k, maxDistance)
+						assert.Equal(t, expected, index.KNearest(center, k, maxDistance, all))
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestPointsBetween(t *testing.T) {
+	for _, center := range []Point{
+		&GeoPoint{"Quito", -0.1807, -78.4678},
+		&GeoPoint{"London", 51.5074, -0.1278},
+		&GeoPoint{"Tromso", 69.6492, 18.9553},
+		&GeoPoint{"Taveuni", -16.8425, 179.9999},
+	} {
+		points := pointsAround(center, 2000, 0.05)
+
+		for _, index := range []*PointsIndex{NewPointsIndex(Km(0.5)), NewHexPointsIndex(Km(0.3), center.Lat()), NewQuadtreePointsIndex(8)} {
+			for _, p := range points {
+				index.Add(p)
+			}
+
+			for _, distances := range [][2]Meters{{0, Km(1)}, {Km(1), Km(3)}, {Km(2), Km(2.5)}, {Km(3), Km(1)}} {
+				expected := make([]Point, 0)
+				for _, p := range bruteForceWithin(points, center, distances[1]) {
+					if Distance(center, p) >= distances[0] {
+						expected = append(expected, p)
+					}
+				}
+
+				assert.True(t, pointsEqualIgnoreOrder(expected, index.PointsBetween(center, distances[0], distances[1], all)))
+			}
+		}
+	}
+}
+
+var (
+	suva               = &GeoPoint{"Suva", -18.1416, 178.4419}
+	levuka             = &GeoPoint{"Levuka", -17.6828, 178.8394}
+	taveuni            = &GeoPoint{"Taveuni", -16.8425, 179.9999}
+	lakeba             = &GeoPoint{"Lakeba", -18.2283, -178.8036}
+	vanuaBal           = &GeoPoint{"Vanua Balavu", -17.2333, -178.95}
+	antimeridianPoints = []Point{suva, levuka, taveuni, lakeba, vanuaBal}
+)
+
+func TestAntimeridianRange(t *testing.T) {
+	for _, index := range pointsIndexes(Km(0.5)) {
+		for _, point := range antimeridianPoints {
+			index.Add(point)
+		}
+
+		topLeft := &GeoPoint{"", -17.0, 178.5}
+		bottomRight := &GeoPoint{"", -18.5, -178.5}
+		assert.True(t, pointsEqualIgnoreOrder([]Point{levuka, lakeba, vanuaBal}, index.Range(topLeft, bottomRight)))
+
+		topLeft = &GeoPoint{"", -16.0, 178.0}
+		bottomRight = &GeoPoint{"", -19.0, -178.0}
+		assert.True(t, pointsEqualIgnoreOrder(antimeridianPoints[:], index.Range(topLeft, bottomRight)))
+
+		topLeft = &GeoPoint{"", -17.0, -178.5}
+		bottomRight = &GeoPoint{"", -17.5, 178.5}
+		assert.Equal(t, 0, len(index.Range(topLeft, bottomRight)))
+	}
+}
+
+func TestAntimeridianNearest(t *testing.T) {
+	for _, index := range pointsIndexes(Km(0.5)) {
+		for _, point := range antimeridianPoints {
+			index.Add(point)
+		}
+
+		dateLine := &GeoPoint{"", -16.85, -179.9999}
+		assert.Equal(t, []Point{taveuni, vanuaBal}, index.KNearest(dateLine, 2, Km(200), all))
+		assert.Equal(t, []Point{taveuni}, index.PointsWithin(dateLine, Km(5), all))
+		assert.True(t, pointsEqualIgnoreOrder([]Point{taveuni, vanuaBal, levuka}, index.PointsWithin(dateLine, Km(160), all)))
+	}
+}
+
+func TestPolarQueries(t *testing.T) {
+	for _, index := range pointsIndexes(Km(0.5)) {
+		nearPole := &GeoPoint{"near pole", 89.95, 0.0}
+		acrossPole := &GeoPoint{"across pole", 89.95, 180.0}
+		sameSide := &GeoPoint{"same side", 89.5, 0.0}
+		subPolar := &GeoPoint{"sub polar", 84.9, 90.0}
+
+		for _, point := range []Point{nearPole, acrossPole, sameSide, subPolar} {
+			index.Add(point)
+		}
+
+		query := &GeoPoint{"", 89.9, 0.0}
+		assert.Equal(t, []Point{nearPole, acrossPole, sameSide}, index.KNearest(query, 3, Km(100), all))
+		assert.True(t, pointsEqualIgnoreOrder([]Point{nearPole, acrossPole}, index.PointsWithin(query, Km(20), all)))
+		assert.True(t, pointsEqualIgnoreOrder([]Point{nearPole, acrossPole, sameSide, subPolar}, index.PointsWithin(query, Km(600), all)))
+
+		southPole := &GeoPoint{"south pole", -90.0, 0.0}
+		index.Add(southPole)
+		assert.Equal(t, []Point{southPole}, index.KNearest(&GeoPoint{"", -89.99, 123.0}, 1, Km(5), all))
+	}
+}
+
+func TestOutOfRangeCoordinates(t *testing.T) {
+	index := NewPointsIndex(Km(0.5))
+
+	wrapped := &GeoPoint{"wrapped", 51.5, 359.9}
+	index.Add(wrapped)
+
+	assert.Equal(t, cellOf(&GeoPoint{"", 51.5, -0.1}, Km(0.5)), cellOf(wrapped, Km(0.5)))
+	assert.Equal(t, cellOf(&GeoPoint{"", 90.0, 0.0}, Km(0.5)), cellOf(&GeoPoint{"", 95.0, 0.0}, Km(0.5)))
+
+	assert.Equal(t, []Point{wrapped}, index.Range(&GeoPoint{"", 52.0, -1.0}, &GeoPoint{"", 51.0, 1.0}))
+	assert.Equal(t, []Point{wrapped}, index.KNearest(&GeoPoint{"", 51.5, -0.1}, 1, Km(1), all))
+}
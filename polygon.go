@@ -0,0 +1,186 @@
+package geoindex
+
+import (
+	"math"
+	"sort"
+)
+
+// Polygon is an area bounded by one or more rings of points. The last point of a ring connects back to the
+// first one. A point is within the polygon when a ray from it crosses the rings an odd number of times, so holes
+// are rings within other rings and multipolygons are disjoint rings. The edges are straight lines on the lat/lon
+// plane and must not cross the antimeridian.
+type Polygon struct {
+	rings [][]Point
+}
+
+// NewPolygon creates a polygon with outer ring outer and optional holes.
+func NewPolygon(outer []Point, holes ...[]Point) *Polygon {
+	rings := make([][]Point, 0, len(holes)+1)
+	rings = append(rings, outer)
+	rings = append(rings, holes...)
+
+	return &Polygon{rings}
+}
+
+// NewMultiPolygon creates a polygon which is the union of disjoint polygons.
+func NewMultiPolygon(polygons ...*Polygon) *Polygon {
+	rings := make([][]Point, 0, len(polygons))
+	for _, polygon := range polygons {
+		rings = append(rings, polygon.rings...)
+	}
+
+	return &Polygon{rings}
+}
+
+// Contains returns true if point is within the polygon.
+func (polygon *Polygon) Contains(point Point) bool {
+	crossings := 0
+
+	polygon.edges(func(a, b Point) {
+		if lon, ok := crossingLon(a, b, point.Lat()); ok && lon < point.Lon() {
+			crossings++
+		}
+	})
+
+	return crossings%2 == 1
+}
+
+// Bounds returns the top left and bottom right corners of the smallest rectangle containing the polygon.
+func (polygon *Polygon) Bounds() (topLeft Point, bottomRight Point) {
+	minLat, maxLat := math.Inf(1), math.Inf(-1)
+	minLon, maxLon := math.Inf(1), math.Inf(-1)
+
+	for _, ring := range polygon.rings {
+		for _, point := range ring {
+			minLat, maxLat = math.Min(minLat, point.Lat()), math.Max(maxLat, point.Lat())
+			minLon, maxLon = math.Min(minLon, point.Lon()), math.Max(maxLon, point.Lon())
+		}
+	}
+
+	return &GeoPoint{"", maxLat, minLon}, &GeoPoint{"", minLat, maxLon}
+}
+
+func (polygon *Polygon) isEmpty() bool {
+	for _, ring := range polygon.rings {
+		if len(ring) > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func (polygon *Polygon) edges(edge func(a, b Point)) {
+	for _, ring := range polygon.rings {
+		for i := range ring {
+			edge(ring[i], ring[(i+1)%len(ring)])
+		}
+	}
+}
+
+// crossingLon returns the longitude where edge a, b crosses latitude lat. An edge includes its southern end but
+// not its northern end, so that a ray through a vertex counts it once.
+func crossingLon(a, b Point, lat float64) (float64, bool) {
+	if (a.Lat() > lat) == (b.Lat() > lat) {
+		return 0, false
+	}
+
+	return a.Lon() + (lat-a.Lat())*(b.Lon()-a.Lon())/(b.Lat()-a.Lat()), true
+}
+
+// segmentIntersectsBox returns true if the segment a, b intersects the rectangle between lat and lon ranges.
+func segmentIntersectsBox(a, b Point, minLat, maxLat, minLon, maxLon float64) bool {
+	// Liang-Barsky clipping of the segment against the rectangle
+	t0, t1 := 0.0, 1.0
+	dLon := b.Lon() - a.Lon()
+	dLat := b.Lat() - a.Lat()
+
+	clip := func(p, q float64) bool {
+		if p == 0 {
+			return q >= 0
+		}
+
+		t := q / p
+		if p < 0 {
+			if t > t1 {
+				return false
+			}
+			t0 = math.Max(t0, t)
+		} else {
+			if t < t0 {
+				return false
+			}
+			t1 = math.Min(t1, t)
+		}
+
+		return true
+	}
+
+	return clip(-dLon, a.Lon()-minLon) && clip(dLon, maxLon-a.Lon()) &&
+		clip(-dLat, a.Lat()-minLat) && clip(dLat, maxLat-a.Lat())
+}
+
+// polygonCells classifies the cells of a scheme covering a polygon. Boundary cells intersect an edge of the
+// polygon, the other cells are either entirely within or entirely outside the polygon.
+type polygonCells struct {
+	polygon  *Polygon
+	scheme   cellScheme
+	boundary map[cell]bool
+	// the sorted longitudes where the edges cross a latitude, by latitude
+	crossings map[float64][]float64
+}
+
+func newPolygonCells(polygon *Polygon, scheme cellScheme) *polygonCells {
+	cells := &polygonCells{polygon, scheme, make(map[cell]bool), make(map[float64][]float64)}
+
+	polygon.edges(func(a, b Point) {
+		edgeTopLeft := &GeoPoint{"", math.Max(a.Lat(), b.Lat()), math.Min(a.Lon(), b.Lon())}
+		edgeBottomRight := &GeoPoint{"", math.Min(a.Lat(), b.Lat()), math.Max(a.Lon(), b.Lon())}
+
+		for _, span := range scheme.rangeCells(edgeTopLeft, edgeBottomRight) {
+			for y := span.minY; y <= span.maxY; y++ {
+				c := cell{span.x, y}
+				if cells.boundary[c] {
+					continue
+				}
+
+				topLeft, bottomRight := scheme.bounds(c)
+				if segmentIntersectsBox(a, b, bottomRight.Lat(), topLeft.Lat(), topLeft.Lon(), bottomRight.Lon()) {
+					cells.boundary[c] = true
+				}
+			}
+		}
+	})
+
+	return cells
+}
+
+// spans returns the spans of cells covering the polygon.
+func (cells *polygonCells) spans() []cellSpan {
+	return cells.scheme.rangeCells(cells.polygon.Bounds())
+}
+
+func (cells *polygonCells) isBoundary(c cell) bool {
+	return cells.boundary[c]
+}
+
+// isInside returns true if c, which must not be a boundary cell, is within the polygon.
+func (cells *polygonCells) isInside(c cell) bool {
+	topLeft, bottomRight := cells.scheme.bounds(c)
+	lat := (topLeft.Lat() + bottomRight.Lat()) / 2
+	lon := (topLeft.Lon() + bottomRight.Lon()) / 2
+
+	crossings, ok := cells.crossings[lat]
+	if !ok {
+		crossings = make([]float64, 0)
+		cells.polygon.edges(func(a, b Point) {
+			if crossingLon, ok := crossingLon(a, b, lat); ok {
+				crossings = append(crossings, crossingLon)
+			}
+		})
+
+		sort.Float64s(crossings)
+		cells.crossings[lat] = crossings
+	}
+
+	return sort.SearchFloat64s(crossings, lon)%2 == 1
+}
@@ -0,0 +1,142 @@
+package geoindex
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	// A rough outline of the London congestion charge zone.
+	congestionZone = NewPolygon([]Point{
+		&GeoPoint{"", 51.5230, -0.1750},
+		&GeoPoint{"", 51.5300, -0.1200},
+		&GeoPoint{"", 51.5250, -0.0750},
+		&GeoPoint{"", 51.5100, -0.0700},
+		&GeoPoint{"", 51.4950, -0.0900},
+		&GeoPoint{"", 51.4880, -0.1350},
+		&GeoPoint{"", 51.4950, -0.1700},
+	})
+
+	// A square around Hyde Park with a hole for the Serpentine.
+	hydePark = NewPolygon([]Point{
+		&GeoPoint{"", 51.5130, -0.1900},
+		&GeoPoint{"", 51.5130, -0.1550},
+		&GeoPoint{"", 51.5020, -0.1550},
+		&GeoPoint{"", 51.5020, -0.1900},
+	}, []Point{
+		&GeoPoint{"", 51.5080, -0.1800},
+		&GeoPoint{"", 51.5080, -0.1650},
+		&GeoPoint{"", 51.5040, -0.1650},
+		&GeoPoint{"", 51.5040, -0.1800},
+	})
+
+	heathrow = NewPolygon([]Point{
+		&GeoPoint{"", 51.4850, -0.4900},
+		&GeoPoint{"", 51.4850, -0.4150},
+		&GeoPoint{"", 51.4600, -0.4150},
+		&GeoPoint{"", 51.4600, -0.4900},
+	})
+)
+
+func bruteForcePolygon(points []Point, polygon *Polygon) []Point {
+	result := make([]Point, 0)
+	for _, p := range points {
+		if polygon.Contains(p) {
+			result = append(result, p)
+		}
+	}
+	return result
+}
+
+func TestPolygonContains(t *testing.T) {
+	assert.True(t, congestionZone.Contains(leicester))
+	assert.True(t, congestionZone.Contains(picadilly))
+	assert.False(t, congestionZone.Contains(&GeoPoint{"", 51.4700, -0.4543}))
+
+	assert.True(t, hydePark.Contains(&GeoPoint{"", 51.5100, -0.1600}))
+	assert.False(t, hydePark.Contains(&GeoPoint{"", 51.5060, -0.1720}))
+	assert.False(t, hydePark.Contains(&GeoPoint{"", 51.5200, -0.1720}))
+
+	airports := NewMultiPolygon(heathrow, hydePark)
+	assert.True(t, airports.Contains(&GeoPoint{"", 51.4700, -0.4543}))
+	assert.True(t, airports.Contains(&GeoPoint{"", 51.5100, -0.1600}))
+	assert.False(t, airports.Contains(&GeoPoint{"", 51.5060, -0.1720}))
+	assert.False(t, airports.Contains(leicester))
+
+	assert.False(t, NewPolygon(nil).Contains(leicester))
+}
+
+func TestPolygonBounds(t *testing.T) {
+	topLeft, bottomRight := NewMultiPolygon(heathrow, hydePark).Bounds()
+
+	assert.Equal(t, 51.5130, topLeft.Lat())
+	assert.Equal(t, -0.4900, topLeft.Lon())
+	assert.Equal(t, 51.4600, bottomRight.Lat())
+	assert.Equal(t, -0.1550, bottomRight.Lon())
+}
+
+func TestPointsIndexWithinPolygon(t *testing.T) {
+	points := pointsAround(&GeoPoint{"london", 51.4900, -0.2800}, 5000, 0.25)
+	polygons := []*Polygon{congestionZone, hydePark, heathrow, NewMultiPolygon(heathrow, hydePark, congestionZone)}
+
+	for _, index := range []*PointsIndex{
+		NewPointsIndex(Km(0.5)),
+		NewPointsIndex(Km(5)),
+		NewHexPointsIndex(Meters(300), 51.5),
+		NewHilbertPointsIndex(14),
+		NewQuadtreePointsIndex(8),
+	} {
+		for _, p := range points {
+			index.Add(p)
+		}
+
+		for _, polygon := range polygons {
+			expected := bruteForcePolygon(points, polygon)
+			assert.NotEmpty(t, expected)
+			assert.ElementsMatch(t, expected, index.WithinPolygon(polygon, all))
+			assert.Equal(t, len(expected), index.CountPolygon(polygon))
+		}
+	}
+}
+
+func TestPointsIndexWithinPolygonAccept(t *testing.T) {
+	index := NewPointsIndex(Km(0.5))
+	for _, station := range tubeStations() {
+		index.Add(station)
+	}
+
+	inZone := index.WithinPolygon(congestionZone, all)
+	assert.Contains(t, inZone, leicester)
+	assert.Contains(t, inZone, picadilly)
+
+	notLeicester := index.WithinPolygon(congestionZone, func(p Point) bool {
+		return p.Id() != leicester.Id()
+	})
+	assert.Equal(t, len(inZone)-1, len(notLeicester))
+	assert.NotContains(t, notLeicester, leicester)
+
+	assert.Equal(t, 3, len(index.WithinPolygon(heathrow, all)))
+	assert.Empty(t, index.WithinPolygon(NewPolygon(nil), all))
+}
+
+func TestCountIndexWithinPolygon(t *testing.T) {
+	points := pointsAround(&GeoPoint{"london", 51.5050, -0.1250}, 2000, 0.05)
+
+	index := NewCountIndex(Km(0.5))
+	for _, p := range points {
+		index.Add(p)
+	}
+
+	total := 0
+	for _, counter := range index.WithinPolygon(congestionZone) {
+		assert.True(t, congestionZone.Contains(counter))
+		total += counter.(*CountPoint).Count.(int)
+	}
+
+	// counters of cells crossed by the edges are either entirely in or out
+	expected := len(bruteForcePolygon(points, congestionZone))
+	assert.InEpsilon(t, expected, total, 0.1)
+
+	assert.Empty(t, index.WithinPolygon(heathrow))
+}
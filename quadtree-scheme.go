@@ -0,0 +1,262 @@
+package geoindex
+
+import (
+	"math"
+	"math/bits"
+)
+
+var (
+	// The quadtree does not split cells beyond this level, about 2.4 by 1.2 m at the equator.
+	quadtreeMaxLevel = 24
+)
+
+// A scheme where the cells are the leaves of a quadtree over the lat/lon plane. A cell is split in 4 once it holds
+// more than capacity points and merged back once its parent holds less than half capacity points. The cells are
+// the same as the cells of CellID. Both the row and the column of a cell start with a 1 bit followed by level bits,
+// so the cells of different levels do not collide.
+type quadtreeScheme struct {
+	capacity int
+	split    map[cell]bool
+	// the number of leaves at each level
+	leaves [MaxCellLevel + 1]int
+}
+
+// NewQuadtreePointsIndex creates new PointsIndex that maintains the points in the leaves of a quadtree, which
+// are split once they hold more than capacity points and merged back once they thin out.
+func NewQuadtreePointsIndex(capacity int) *PointsIndex {
+	newSet := func() interface{} {
+		return newSet()
+	}
+
+	return &PointsIndex{newSchemeGeoIndex(newQuadtreeScheme(capacity), newSet), make(map[string]Point)}
+}
+
+func newQuadtreeScheme(capacity int) *quadtreeScheme {
+	if capacity < 1 {
+		panic("Quadtree capacity must be at least 1")
+	}
+
+	q := &quadtreeScheme{capacity: capacity, split: make(map[cell]bool)}
+	q.leaves[0] = 1
+
+	return q
+}
+
+// A cellScheme whose cells change as points are added to and removed from the index.
+type adaptiveScheme interface {
+	cellScheme
+
+	// added is called after a point is added to the entry of c.
+	added(index *geoIndex, c cell)
+
+	// removed is called after a point is removed from the entry of c.
+	removed(index *geoIndex, c cell)
+
+	// clone returns a copy of the scheme that changes independently.
+	clone() cellScheme
+}
+
+func quadtreeCell(level int, row int, column int) cell {
+	return cell{1<<uint(level) | row, 1<<uint(level) | column}
+}
+
+func quadtreeLevel(c cell) int {
+	return bits.Len(uint(c.x)) - 1
+}
+
+func quadtreeChildren(c cell) [4]cell {
+	return [4]cell{
+		{c.x << 1, c.y << 1},
+		{c.x << 1, c.y<<1 | 1},
+		{c.x<<1 | 1, c.y << 1},
+		{c.x<<1 | 1, c.y<<1 | 1},
+	}
+}
+
+func quadtreeParent(c cell) cell {
+	return cell{c.x >> 1, c.y >> 1}
+}
+
+// nodeAt returns the node at level that contains point, regardless of whether it is a leaf.
+func (q *quadtreeScheme) nodeAt(point Point, level int) cell {
+	cells := float64(int(1) << uint(level))
+	row := int(math.Min(cells-1, (normalizeLat(point.Lat())-minLat)/180*cells))
+	column := int(math.Min(cells-1, (normalizeLon(point.Lon())-minLon)/360*cells))
+
+	return quadtreeCell(level, row, column)
+}
+
+func (q *quadtreeScheme) cellOf(point Point) cell {
+	level := 0
+	for q.split[q.nodeAt(point, level)] {
+		level++
+	}
+
+	return q.nodeAt(point, level)
+}
+
+func (q *quadtreeScheme) neighbours(c cell) []cell {
+	topLeft, bottomRight := q.bounds(c)
+
+	epsilon := 1e-9
+	spans := q.boxSpans(bottomRight.Lat()-epsilon, topLeft.Lat()+epsilon, topLeft.Lon()-epsilon, bottomRight.Lon()+epsilon)
+
+	result := make([]cell, 0, 8)
+	for _, span := range spans {
+		if (cell{span.x, span.minY}) != c {
+			result = append(result, cell{span.x, span.minY})
+		}
+	}
+
+	return result
+}
+
+func (q *quadtreeScheme) rangeCells(topLeft Point, bottomRight Point) []cellSpan {
+	return q.boxSpans(bottomRight.Lat(), topLeft.Lat(), topLeft.Lon(), bottomRight.Lon())
+}
+
+func (q *quadtreeScheme) aroundCells(point Point, distance Meters) []cellSpan {
+	return q.boxSpans(aroundBox(point, distance))
+}
+
+// boxSpans returns a span for each leaf that intersects the rectangle between lat and lon ranges.
+func (q *quadtreeScheme) boxSpans(minLat, maxLat, minLon, maxLon float64) []cellSpan {
+	spans := make([]cellSpan, 0)
+
+	for _, interval := range lonIntervals(minLon, maxLon) {
+		spans = q.appendLeaves(spans, quadtreeCell(0, 0, 0), minLat, maxLat, interval[0], interval[1])
+	}
+
+	return spans
+}
+
+func (q *quadtreeScheme) appendLeaves(spans []cellSpan, c cell, minLat, maxLat, minLon, maxLon float64) []cellSpan {
+	topLeft, bottomRight := q.bounds(c)
+
+	if topLeft.Lat() < minLat || bottomRight.Lat() > maxLat || bottomRight.Lon() < minLon || topLeft.Lon() > maxLon {
+		return spans
+	}
+
+	if !q.split[c] {
+		return append(spans, cellSpan{c.x, c.y, c.y})
+	}
+
+	for _, child := range quadtreeChildren(c) {
+		spans = q.appendLeaves(spans, child, minLat, maxLat, minLon, maxLon)
+	}
+
+	return spans
+}
+
+func (q *quadtreeScheme) bounds(c cell) (topLeft Point, bottomRight Point) {
+	level := quadtreeLevel(c)
+	cells := float64(int(1) << uint(level))
+	row := c.x &^ (1 << uint(level))
+	column := c.y &^ (1 << uint(level))
+
+	height := 180 / cells
+	width := 360 / cells
+	bottom := minLat + float64(row)*height
+	left := minLon + float64(column)*width
+
+	return &GeoPoint{"", bottom + height, left}, &GeoPoint{"", bottom, left + width}
+}
+
+// size returns the size of the smallest leaves.
+func (q *quadtreeScheme) size() Meters {
+	level := 0
+	for l := range q.leaves {
+		if q.leaves[l] > 0 {
+			level = l
+		}
+	}
+
+	cells := float64(int(1) << uint(level))
+	height := 180 / cells * float64(latDegreeLength)
+	width := 360 / cells * float64(lonLength.get(0))
+
+	return Meters(math.Min(height, width))
+}
+
+func (q *quadtreeScheme) clone() cellScheme {
+	clone := &quadtreeScheme{capacity: q.capacity, split: make(map[cell]bool, len(q.split)), leaves: q.leaves}
+	for k, v := range q.split {
+		clone.split[k] = v
+	}
+
+	return clone
+}
+
+func (q *quadtreeScheme) added(index *geoIndex, c cell) {
+	entry, ok := index.index[c]
+	if ok && entry.(set).Size() > q.capacity && quadtreeLevel(c) < quadtreeMaxLevel {
+		q.splitCell(index, c)
+	}
+}
+
+func (q *quadtreeScheme) removed(index *geoIndex, c cell) {
+	for quadtreeLevel(c) > 0 {
+		parent := quadtreeParent(c)
+
+		count := 0
+		for _, child := range quadtreeChildren(parent) {
+			if q.split[child] {
+				return
+			}
+
+			if entry, ok := index.index[child]; ok {
+				count += entry.(set).Size()
+			}
+		}
+
+		if count > q.capacity/2 {
+			return
+		}
+
+		q.mergeCell(index, parent)
+		c = parent
+	}
+}
+
+// splitCell splits leaf c in 4 and moves its points to the new leaves, which are split further if needed.
+func (q *quadtreeScheme) splitCell(index *geoIndex, c cell) {
+	level := quadtreeLevel(c)
+	q.split[c] = true
+	q.leaves[level]--
+	q.leaves[level+1] += 4
+
+	entry, ok := index.index[c]
+	if !ok {
+		return
+	}
+	delete(index.index, c)
+
+	for _, value := range entry.(set).Values() {
+		point := value.(Point)
+		index.AddEntryAt(point).(set).Add(point.Id(), point)
+	}
+
+	for _, child := range quadtreeChildren(c) {
+		q.added(index, child)
+	}
+}
+
+// mergeCell merges the 4 leaves of c back in c.
+func (q *quadtreeScheme) mergeCell(index *geoIndex, c cell) {
+	level := quadtreeLevel(c)
+	delete(q.split, c)
+	q.leaves[level]++
+	q.leaves[level+1] -= 4
+
+	merged := index.newEntry().(set)
+	for _, child := range quadtreeChildren(c) {
+		if entry, ok := index.index[child]; ok {
+			for _, value := range entry.(set).Values() {
+				merged.Add(value.(Point).Id(), value)
+			}
+			delete(index.index, child)
+		}
+	}
+
+	index.index[c] = merged
+}
@@ -0,0 +1,48 @@
+package geoindex
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQuadtreeSplitAndMerge(t *testing.T) {
+	index := NewQuadtreePointsIndex(8)
+	scheme := index.index.scheme.(*quadtreeScheme)
+
+	added := make([]Point, 0)
+	for i := 0; i < 2000; i++ {
+		point := randomPoint()
+		added = append(added, point)
+		index.Add(point)
+	}
+
+	clone := index.Clone()
+
+	total := 0
+	for c, entry := range index.index.index {
+		assert.False(t, scheme.split[c])
+		assert.True(t, entry.(set).Size() <= 8)
+		total += entry.(set).Size()
+	}
+	assert.Equal(t, len(added), total)
+
+	leaf := scheme.cellOf(charring)
+	neighbours := scheme.neighbours(leaf)
+	assert.True(t, len(neighbours) >= 4)
+	for _, neighbour := range neighbours {
+		assert.False(t, scheme.split[neighbour])
+		assert.NotEqual(t, leaf, neighbour)
+	}
+
+	for _, point := range added {
+		assert.Equal(t, point, index.Get(point.Id()))
+		index.Remove(point.Id())
+	}
+
+	assert.Equal(t, 0, len(scheme.split))
+	assert.Equal(t, 1, scheme.leaves[0])
+
+	// the clone keeps its own quadtree
+	assert.Equal(t, len(added), len(clone.Range(&GeoPoint{"", 90, -180}, &GeoPoint{"", -90, 180})))
+}
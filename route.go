@@ -0,0 +1,130 @@
+package geoindex
+
+import (
+	"math"
+	"sort"
+)
+
+// routePoint is a point near a route with the distance along the route to the point of the route closest to it.
+type routePoint struct {
+	point    Point
+	progress Meters
+}
+
+type routePoints []routePoint
+
+func (points routePoints) Len() int {
+	return len(points)
+}
+
+func (points routePoints) Swap(i, j int) {
+	points[i], points[j] = points[j], points[i]
+}
+
+func (points routePoints) Less(i, j int) bool {
+	return points[i].progress < points[j].progress
+}
+
+// AlongRoute returns all points within width of the route through path that match the accept criteria, ordered by
+// their progress along the route. The legs of the route are great circle arcs. When the route passes near a point
+// more than once, the point is returned once, ordered by the first time the route passes near it.
+func (points *PointsIndex) AlongRoute(path []Point, width Meters, accept func(p Point) bool) []Point {
+	nearbyPoints := getPoints(points.index.Corridor(path, width), accept)
+
+	withinPoints := make(routePoints, 0, len(nearbyPoints))
+	for _, nearbyPoint := range nearbyPoints {
+		if progress, ok := routeProgress(path, nearbyPoint, width); ok {
+			withinPoints = append(withinPoints, routePoint{nearbyPoint, progress})
+		}
+	}
+
+	sort.Stable(withinPoints)
+
+	result := make([]Point, len(withinPoints))
+	for i, withinPoint := range withinPoints {
+		result[i] = withinPoint.point
+	}
+
+	return result
+}
+
+// routeLegs calls leg with the start and the end of each leg of the route through path. A path with a single point
+// is a route with a single leg of zero length.
+func routeLegs(path []Point, leg func(a, b Point)) {
+	if len(path) == 1 {
+		leg(path[0], path[0])
+	}
+
+	for i := 1; i < len(path); i++ {
+		leg(path[i-1], path[i])
+	}
+}
+
+// routeProgress returns the distance along the route through path to the first point of the route which is
+// within width of point.
+func routeProgress(path []Point, point Point, width Meters) (Meters, bool) {
+	travelled := Meters(0)
+	progress := Meters(0)
+	found := false
+
+	routeLegs(path, func(a, b Point) {
+		if found {
+			return
+		}
+
+		if dist, along := legDistance(a, b, point); dist <= width {
+			progress = travelled + along
+			found = true
+		}
+
+		travelled += Distance(a, b)
+	})
+
+	return progress, found
+}
+
+// legDistance returns the distance from point to the closest point of the great circle arc from a to b and the
+// distance from a to that closest point along the arc.
+func legDistance(a, b, point Point) (dist Meters, along Meters) {
+	legLength := Distance(a, b)
+	toPoint := Distance(a, point)
+	angleToPoint := float64(toPoint / earthRadius)
+
+	bearing := toRadians(BearingTo(a, point) - BearingTo(a, b))
+	crossTrack := math.Asin(math.Sin(angleToPoint) * math.Sin(bearing))
+
+	alongTrack := Meters(math.Acos(math.Min(1, math.Cos(angleToPoint)/math.Cos(crossTrack)))) * earthRadius
+	if math.Cos(bearing) < 0 {
+		alongTrack = -alongTrack
+	}
+
+	if alongTrack <= 0 {
+		return toPoint, 0
+	}
+
+	if alongTrack >= legLength {
+		return Distance(b, point), legLength
+	}
+
+	return Meters(math.Abs(crossTrack)) * earthRadius, alongTrack
+}
+
+// intermediatePoint returns the point at fraction of the great circle arc from a to b.
+func intermediatePoint(a, b Point, fraction float64) Point {
+	angle := float64(Distance(a, b) / earthRadius)
+	if angle == 0 {
+		return a
+	}
+
+	lat1, lon1 := toRadians(a.Lat()), toRadians(a.Lon())
+	lat2, lon2 := toRadians(b.Lat()), toRadians(b.Lon())
+
+	weightA := math.Sin((1-fraction)*angle) / math.Sin(angle)
+	weightB := math.Sin(fraction*angle) / math.Sin(angle)
+
+	x := weightA*math.Cos(lat1)*math.Cos(lon1) + weightB*math.Cos(lat2)*math.Cos(lon2)
+	y := weightA*math.Cos(lat1)*math.Sin(lon1) + weightB*math.Cos(lat2)*math.Sin(lon2)
+	z := weightA*math.Sin(lat1) + weightB*math.Sin(lat2)
+
+	return &GeoPoint{"", toDegrees(math.Atan2(z, math.Sqrt(x*x+y*y))), toDegrees(math.Atan2(y, x))}
+}
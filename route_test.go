@@ -0,0 +1,133 @@
+package geoindex
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	// From Kings Cross to Victoria through Oxford Circus and Green Park.
+	route = []Point{
+		&GeoPoint{"", 51.5308, -0.1238},
+		&GeoPoint{"", 51.5152, -0.1418},
+		&GeoPoint{"", 51.5067, -0.1428},
+		&GeoPoint{"", 51.4965, -0.1447},
+	}
+)
+
+func bruteForceAlongRoute(points []Point, path []Point, width Meters) []Point {
+	result := make([]Point, 0)
+	for _, p := range points {
+		if _, ok := routeProgress(path, p, width); ok {
+			result = append(result, p)
+		}
+	}
+	return result
+}
+
+func TestLegDistance(t *testing.T) {
+	a := &GeoPoint{"", 0, 0}
+	b := &GeoPoint{"", 0, 1}
+
+	dist, along := legDistance(a, b, &GeoPoint{"", 0, 0.5})
+	assert.InDelta(t, 0, float64(dist), 0.01)
+	assert.InDelta(t, float64(Distance(a, b))/2, float64(along), 0.01)
+
+	dist, along = legDistance(a, b, &GeoPoint{"", 0.01, 0.25})
+	assert.InDelta(t, 1112, float64(dist), 1)
+	assert.InDelta(t, float64(Distance(a, b))/4, float64(along), 1)
+
+	dist, along = legDistance(a, b, &GeoPoint{"", 0.01, -0.5})
+	assert.Equal(t, Distance(a, &GeoPoint{"", 0.01, -0.5}), dist)
+	assert.Equal(t, Meters(0), along)
+
+	dist, along = legDistance(a, b, &GeoPoint{"", -0.01, 1.5})
+	assert.Equal(t, Distance(b, &GeoPoint{"", -0.01, 1.5}), dist)
+	assert.Equal(t, Distance(a, b), along)
+
+	dist, along = legDistance(a, a, b)
+	assert.Equal(t, Distance(a, b), dist)
+	assert.Equal(t, Meters(0), along)
+}
+
+func TestIntermediatePoint(t *testing.T) {
+	a := &GeoPoint{"", 51.5308, -0.1238}
+	b := &GeoPoint{"", 40.6413, -73.7781}
+
+	assert.InDelta(t, 0, float64(Distance(a, intermediatePoint(a, b, 0))), 0.01)
+	assert.InDelta(t, 0, float64(Distance(b, intermediatePoint(a, b, 1))), 0.01)
+
+	middle := intermediatePoint(a, b, 0.5)
+	assert.InDelta(t, float64(Distance(a, middle)), float64(Distance(middle, b)), 0.01)
+	// the great circle goes north of both ends
+	assert.True(t, middle.Lat() > a.Lat())
+}
+
+func TestAlongRoute(t *testing.T) {
+	points := pointsAround(&GeoPoint{"london", 51.5136, -0.1340}, 5000, 0.03)
+
+	for _, index := range []*PointsIndex{
+		NewPointsIndex(Km(0.5)),
+		NewPointsIndex(Meters(100)),
+		NewHexPointsIndex(Meters(300), 51.5),
+		NewHilbertPointsIndex(16),
+		NewQuadtreePointsIndex(8),
+	} {
+		for _, p := range points {
+			index.Add(p)
+		}
+
+		for _, width := range []Meters{Meters(50), Meters(300)} {
+			expected := bruteForceAlongRoute(points, route, width)
+			assert.NotEmpty(t, expected)
+
+			alongRoute := index.AlongRoute(route, width, all)
+			assert.ElementsMatch(t, expected, alongRoute)
+
+			for i := 1; i < len(alongRoute); i++ {
+				previous, _ := routeProgress(route, alongRoute[i-1], width)
+				current, _ := routeProgress(route, alongRoute[i], width)
+				assert.True(t, previous <= current)
+			}
+		}
+	}
+}
+
+func TestAlongRouteOrder(t *testing.T) {
+	index := NewPointsIndex(Km(0.5))
+	for _, station := range tubeStations() {
+		index.Add(station)
+	}
+
+	alongRoute := index.AlongRoute(route, Meters(200), func(p Point) bool {
+		return p.Id() != "Green Park"
+	})
+
+	assert.Equal(t, "Kings Cross St. Pancras", alongRoute[0].Id())
+	assert.Equal(t, "Victoria", alongRoute[len(alongRoute)-1].Id())
+	assert.Contains(t, alongRoute, oxford)
+	assert.NotContains(t, alongRoute, index.Get("Green Park"))
+
+	reversed := make([]Point, len(route))
+	for i, p := range route {
+		reversed[len(route)-1-i] = p
+	}
+	assert.Equal(t, "Victoria", index.AlongRoute(reversed, Meters(200), all)[0].Id())
+
+	assert.Empty(t, index.AlongRoute(nil, Meters(200), all))
+	assert.Equal(t, []Point{oxford}, index.AlongRoute([]Point{oxford}, Meters(10), all))
+}
+
+func TestAlongRouteAntimeridian(t *testing.T) {
+	index := NewPointsIndex(Km(1))
+	for _, p := range antimeridianPoints {
+		index.Add(p)
+	}
+
+	path := []Point{&GeoPoint{"", -17.5, 178.0}, &GeoPoint{"", -17.5, -178.0}}
+	expected := bruteForceAlongRoute(antimeridianPoints, path, Km(100))
+
+	assert.NotEmpty(t, expected)
+	assert.ElementsMatch(t, expected, index.AlongRoute(path, Km(100), all))
+}
@@ -0,0 +1,93 @@
+package geoindex
+
+import (
+	"math"
+	"sort"
+)
+
+// InSector returns the points within the sector of the circle with radius maxDistance centered at origin, between
+// bearing-halfAngle and bearing+halfAngle, that match the accept criteria, sorted by distance. The bearings are in
+// degrees clockwise from north, like BearingTo. The points at origin are within any sector.
+func (points *PointsIndex) InSector(origin Point, bearing, halfAngle float64, maxDistance Meters, accept func(p Point) bool) []Point {
+	minLat, maxLat, minLon, maxLon := sectorBox(origin, bearing, halfAngle, maxDistance)
+
+	inSector := make(byDistance, 0)
+	points.RangeFunc(&GeoPoint{"", maxLat, minLon}, &GeoPoint{"", minLat, maxLon}, func(point Point) bool {
+		if !accept(point) {
+			return true
+		}
+
+		dist := Distance(origin, point)
+		if dist <= maxDistance && (dist == 0 || bearingDelta(BearingTo(origin, point), bearing) <= halfAngle) {
+			inSector = append(inSector, PointDistance{Point: point, Distance: dist})
+		}
+
+		return true
+	})
+
+	sort.Stable(inSector)
+
+	result := make([]Point, len(inSector))
+	for i, point := range inSector {
+		result[i] = point.Point
+	}
+
+	return result
+}
+
+// bearingDelta returns the angle between two bearings in degrees, from 0 to 180.
+func bearingDelta(bearing1, bearing2 float64) float64 {
+	return lonDelta(bearing1, bearing2)
+}
+
+// destination returns the point at distance from origin along the great circle with the initial bearing.
+func destination(origin Point, bearing float64, distance Meters) Point {
+	angle := float64(distance / earthRadius)
+	lat := toRadians(origin.Lat())
+	theta := toRadians(bearing)
+
+	destLat := math.Asin(math.Sin(lat)*math.Cos(angle) + math.Cos(lat)*math.Sin(angle)*math.Cos(theta))
+	dLon := math.Atan2(math.Sin(theta)*math.Sin(angle)*math.Cos(lat), math.Cos(angle)-math.Sin(lat)*math.Sin(destLat))
+
+	return &GeoPoint{"", toDegrees(destLat), origin.Lon() + toDegrees(dLon)}
+}
+
+var (
+	// The number of points sampled along each radius of a sector and each 10 degrees of its arc.
+	sectorSamples = 16
+)
+
+// sectorBox returns a rectangle containing the sector of the circle with radius distance centered at origin,
+// between bearing-halfAngle and bearing+halfAngle. The longitudes can be beyond the antimeridian.
+func sectorBox(origin Point, bearing, halfAngle float64, distance Meters) (minLat, maxLat, minLon, maxLon float64) {
+	minLat, maxLat, minLon, maxLon = aroundBox(origin, distance)
+
+	// a sector which can contain a pole spans all longitudes, like its circle
+	if halfAngle >= 180 || maxLon-minLon >= 360 {
+		return minLat, maxLat, minLon, maxLon
+	}
+
+	// The sector has the same bounding rectangle as its outline, which is sampled. Every point of the outline is
+	// within half the spacing of the samples from one of them.
+	arcSamples := int(math.Max(1, math.Ceil(2*halfAngle/10))) * sectorSamples
+	spacing := math.Max(float64(distance)/float64(sectorSamples), float64(distance)*toRadians(2*halfAngle)/float64(arcSamples))
+
+	minLat, maxLat = origin.Lat(), origin.Lat()
+	minLon, maxLon = origin.Lon(), origin.Lon()
+	extend := func(point Point) {
+		minLat, maxLat = math.Min(minLat, point.Lat()), math.Max(maxLat, point.Lat())
+		minLon, maxLon = math.Min(minLon, point.Lon()), math.Max(maxLon, point.Lon())
+	}
+
+	for i := 1; i <= sectorSamples; i++ {
+		radius := distance * Meters(i) / Meters(sectorSamples)
+		extend(destination(origin, bearing-halfAngle, radius))
+		extend(destination(origin, bearing+halfAngle, radius))
+	}
+
+	for i := 0; i <= arcSamples; i++ {
+		extend(destination(origin, bearing-halfAngle+2*halfAngle*float64(i)/float64(arcSamples), distance))
+	}
+
+	return aroundRectBox(&GeoPoint{"", maxLat, minLon}, &GeoPoint{"", minLat, maxLon}, Meters(spacing/2))
+}
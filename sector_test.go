@@ -0,0 +1,89 @@
+package geoindex
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func bruteForceSector(points []Point, origin Point, bearing, halfAngle float64, maxDistance Meters) []Point {
+	result := make([]Point, 0)
+	for _, p := range points {
+		d := Distance(origin, p)
+		if d <= maxDistance && (d == 0 || bearingDelta(BearingTo(origin, p), bearing) <= halfAngle) {
+			result = append(result, p)
+		}
+	}
+	return result
+}
+
+func TestDestination(t *testing.T) {
+	for _, bearing := range []float64{0, 45, 90, 135, 180, -90, -30} {
+		dest := destination(charring, bearing, Km(10))
+		assert.InDelta(t, 10000, float64(Distance(charring, dest)), 0.01)
+		assert.InDelta(t, 0, bearingDelta(bearing, BearingTo(charring, dest)), 1e-6)
+	}
+}
+
+func TestBearingDelta(t *testing.T) {
+	assert.Equal(t, 20.0, bearingDelta(170, -170))
+	assert.Equal(t, 90.0, bearingDelta(-45, 45))
+	assert.Equal(t, 180.0, bearingDelta(0, 180))
+	assert.Equal(t, 10.0, bearingDelta(355, 5))
+}
+
+func TestInSector(t *testing.T) {
+	rand.Seed(19)
+
+	for _, origin := range []Point{
+		charring,
+		&GeoPoint{"Taveuni", -16.8425, 179.9999},
+		&GeoPoint{"Longyearbyen", 78.2232, 15.6267},
+	} {
+		points := pointsAround(origin, 3000, 0.5)
+
+		for _, index := range []*PointsIndex{NewPointsIndex(Km(1)), NewHilbertPointsIndex(14)} {
+			for _, p := range points {
+				index.Add(p)
+			}
+
+			for _, bearing := range []float64{0, 80, 180, -100} {
+				for _, halfAngle := range []float64{0, 15, 60, 120, 180} {
+					for _, maxDistance := range []Meters{Km(5), Km(40)} {
+						expected := bruteForceSector(points, origin, bearing, halfAngle, maxDistance)
+						inSector := index.InSector(origin, bearing, halfAngle, maxDistance, all)
+
+						assert.True(t, pointsEqualIgnoreOrder(expected, inSector))
+						assert.Equal(t, len(expected), len(inSector))
+						for i := 1; i < len(inSector); i++ {
+							assert.True(t, Distance(origin, inSector[i-1]) <= Distance(origin, inSector[i]))
+						}
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestInSectorMotorway(t *testing.T) {
+	index := NewPointsIndex(Km(0.5))
+	for _, station := range tubeStations() {
+		index.Add(station)
+	}
+
+	// heading north from Waterloo
+	origin := index.Get("Waterloo")
+	ahead := index.InSector(origin, 0, 20, Km(3.5), all)
+	assert.Equal(t, "Waterloo", ahead[0].Id())
+	assert.Contains(t, ahead, index.Get("Kings Cross St. Pancras"))
+	assert.NotContains(t, ahead, index.Get("Victoria"))
+
+	behind := index.InSector(origin, 180, 20, Km(3.5), func(p Point) bool {
+		return p.Id() != "Waterloo"
+	})
+	for _, p := range behind {
+		assert.True(t, p.Lat() < origin.Lat())
+	}
+	assert.NotContains(t, behind, index.Get("Kings Cross St. Pancras"))
+}
@@ -10,6 +10,8 @@ type set interface {
 	Get(id string) (value interface{}, ok bool)
 	Remove(id string)
 	Values() []interface{}
+	// Visit calls visitor with each value until visitor returns false, which Visit returns.
+	Visit(visitor func(value interface{}) bool) bool
 	Size() int
 	Clone() set
 }
@@ -49,6 +51,16 @@ func (set basicSet) Values() []interface{} {
 	return result
 }
 
+func (set basicSet) Visit(visitor func(value interface{}) bool) bool {
+	for _, value := range set {
+		if !visitor(value) {
+			return false
+		}
+	}
+
+	return true
+}
+
 func (set basicSet) Get(id string) (value interface{}, ok bool) {
 	value, ok = set[id]
 	return
@@ -137,6 +149,11 @@ func (set *expiringSet) Values() []interface{} {
 	return set.values.Values()
 }
 
+func (set *expiringSet) Visit(visitor func(value interface{}) bool) bool {
+	set.expire()
+	return set.values.Visit(visitor)
+}
+
 func (set *expiringSet) OnExpire(onExpire func(id string, value interface{})) {
 	set.onExpire = onExpire
 }
@@ -34,6 +34,27 @@ func TestSet(t *testing.T) {
 	assert.True(t, pointsEqualIgnoreOrder(toPoints(set.Values()), []Point{picadilly, embankment, oxford}))
 }
 
+func TestSetVisit(t *testing.T) {
+	set := newSet()
+	set.Add(picadilly.Id(), picadilly)
+	set.Add(oxford.Id(), oxford)
+	set.Add(embankment.Id(), embankment)
+
+	visited := make([]Point, 0)
+	assert.True(t, set.Visit(func(value interface{}) bool {
+		visited = append(visited, value.(Point))
+		return true
+	}))
+	assert.True(t, pointsEqualIgnoreOrder(toPoints(set.Values()), visited))
+
+	count := 0
+	assert.False(t, set.Visit(func(value interface{}) bool {
+		count++
+		return count < 2
+	}))
+	assert.Equal(t, 2, count)
+}
+
 func toPoints(values []interface{}) []Point {
 	result := make([]Point, 0)
 	for _, value := range values {